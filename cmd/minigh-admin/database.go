@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+)
+
+// reindex rebuilds the indexes of the named tables, or of every table.
+// REINDEX locks out writes to a table while it runs.
+func reindex(dbConn *db.DB, args []string) error {
+	var tables []string
+	if err := dbConn.Raw("SELECT tablename FROM pg_tables WHERE schemaname = current_schema() ORDER BY tablename").Scan(&tables).Error; err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		for _, t := range args {
+			if !slices.Contains(tables, t) {
+				return fmt.Errorf("no table %s", t)
+			}
+		}
+		tables = args
+	}
+
+	for _, t := range tables {
+		if err := dbConn.Exec(`REINDEX TABLE "` + strings.ReplaceAll(t, `"`, `""`) + `"`).Error; err != nil {
+			return fmt.Errorf("reindexing %s: %w", t, err)
+		}
+		fmt.Printf("reindexed %s\n", t)
+	}
+	return nil
+}
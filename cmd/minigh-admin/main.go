@@ -0,0 +1,104 @@
+// Command minigh-admin operates a mini-github server: it manages users and
+// repositories, migrates the database and runs maintenance. It reads the same
+// configuration as the server and talks to the database and repository
+// storage directly, so it must run where the server's data is reachable.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/GordenArcher/mini-github/internal/config"
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/migrations"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: minigh-admin [-config file] <command> [arguments]
+
+Users:
+  user create -username name -email address [-password password] [-verified]
+  user verify <username>
+  user disable <username>
+  user enable <username>
+  user reset-password [-password password] <username>
+
+Repositories:
+  repo list [-owner username]
+  repo delete [-yes] <owner>/<name>
+  repo reassign <owner>/<name> <new owner>
+
+Database:
+  migrate up | down [n] | status | to <version>
+  reindex [table...]
+
+Maintenance:
+  maintenance [<owner>/<name>...]
+
+Without -password, a random password is generated and printed.
+Flags go before the other arguments of a command.
+`
+
+// errUsage makes main print the usage text
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	godotenv.Load()
+
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*configPath, flag.Args()); err != nil {
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		fmt.Fprintln(os.Stderr, "minigh-admin:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, args []string) error {
+	// the database hooks log through the shared logger
+	if err := log.Init(); err != nil {
+		return err
+	}
+	defer log.Sync()
+
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	dbConn := db.Connect(cfg.DatabaseURL)
+	storage.Use(storage.NewLocal(cfg.ReposPath))
+
+	cmd, args := args[0], args[1:]
+	if cmd == "migrate" {
+		return migrations.Command(dbConn, args, os.Stdout)
+	}
+
+	// everything else needs the schema this build expects
+	if err := migrations.Check(dbConn); err != nil {
+		return err
+	}
+	switch cmd {
+	case "user":
+		return userCommand(dbConn, args)
+	case "repo":
+		return repoCommand(dbConn, args)
+	case "reindex":
+		return reindex(dbConn, args)
+	case "maintenance":
+		return runMaintenance(dbConn, args)
+	}
+	return errUsage
+}
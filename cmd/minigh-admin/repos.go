@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/forks"
+	"github.com/GordenArcher/mini-github/internal/maintenance"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"gorm.io/gorm"
+)
+
+func repoCommand(dbConn *db.DB, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		return listRepos(dbConn, args[1:])
+	case "delete":
+		return deleteRepo(dbConn, args[1:])
+	case "reassign":
+		return reassignRepo(dbConn, args[1:])
+	}
+	return errUsage
+}
+
+func listRepos(dbConn *db.DB, args []string) error {
+	fs := flag.NewFlagSet("repo list", flag.ContinueOnError)
+	owner := fs.String("owner", "", "only repositories of this user")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	q := dbConn.Preload("Owner").Order("id")
+	if *owner != "" {
+		user, err := findUser(dbConn, *owner)
+		if err != nil {
+			return err
+		}
+		q = q.Where("owner_id = ?", user.ID)
+	}
+	var repos []db.Repository
+	if err := q.Find(&repos).Error; err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tREPOSITORY\tVISIBILITY\tPATH\tCREATED")
+	for _, r := range repos {
+		fmt.Fprintf(w, "%d\t%s/%s\t%s\t%s\t%s\n", r.ID, r.Owner.Username, r.Name, r.Visibility, r.Path, r.CreatedAt.Format("2006-01-02"))
+	}
+	return w.Flush()
+}
+
+func deleteRepo(dbConn *db.DB, args []string) error {
+	fs := flag.NewFlagSet("repo delete", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	repo, err := findRepo(dbConn, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if !*yes && !confirm(fmt.Sprintf("Delete %s with all its history, webhooks and alerts?", fs.Arg(0))) {
+		return errors.New("aborted")
+	}
+	// forks stop borrowing its objects first
+	if err := forks.Detach(context.Background(), dbConn, repo); err != nil {
+		return fmt.Errorf("copying objects into forks: %w", err)
+	}
+	if err := dbConn.DeleteRepository(repo); err != nil {
+		return err
+	}
+	// repositories predating storage locations may have nothing on disk
+	if repo.Path != "" {
+		if err := storage.Delete(repo.Path); err != nil {
+			return fmt.Errorf("deleted %s from the database but not from storage: %w", fs.Arg(0), err)
+		}
+	}
+	fmt.Printf("deleted %s\n", fs.Arg(0))
+	return nil
+}
+
+func reassignRepo(dbConn *db.DB, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	repo, err := findRepo(dbConn, args[0])
+	if err != nil {
+		return err
+	}
+	to, err := findUser(dbConn, args[1])
+	if err != nil {
+		return err
+	}
+	if to.ID == repo.OwnerID {
+		return fmt.Errorf("%s already owns %s", to.Username, args[0])
+	}
+	if taken, err := dbConn.RepositoryNameTaken(to.ID, repo.Name); err != nil {
+		return err
+	} else if taken {
+		return fmt.Errorf("%s already has a repository named %s", to.Username, repo.Name)
+	}
+
+	// repositories predating storage locations may have nothing on disk to move
+	from, path := repo.Path, ""
+	if from != "" {
+		path = storage.Location(to.ID, repo.Name)
+		if err := storage.Move(from, path); err != nil {
+			return fmt.Errorf("moving the repository: %w", err)
+		}
+	}
+	if err := dbConn.MoveRepository(repo, to, repo.Name, path); err != nil {
+		if from == "" {
+			return err
+		}
+		if moveErr := storage.Move(path, from); moveErr != nil {
+			return fmt.Errorf("%w, and moving the repository back to %s failed: %v", err, from, moveErr)
+		}
+		return err
+	}
+	if err := forks.Relink(dbConn, repo); err != nil {
+		fmt.Fprintf(os.Stderr, "relinking forks: %v\n", err)
+	}
+	fmt.Printf("%s is now %s/%s\n", args[0], to.Username, repo.Name)
+	return nil
+}
+
+// runMaintenance runs maintenance now on the named repositories, or on all of them
+func runMaintenance(dbConn *db.DB, args []string) error {
+	var repos []*db.Repository
+	if len(args) == 0 {
+		if err := dbConn.Preload("Owner").Order("id").Find(&repos).Error; err != nil {
+			return err
+		}
+	}
+	for _, name := range args {
+		repo, err := findRepo(dbConn, name)
+		if err != nil {
+			return err
+		}
+		repos = append(repos, repo)
+	}
+
+	failed := 0
+	for _, repo := range repos {
+		name := repo.Owner.Username + "/" + repo.Name
+		if repo.Path == "" {
+			continue
+		}
+		if err := maintenance.Run(context.Background(), storage.Path(repo.Path)); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Println(name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("maintenance failed for %d of %d repositories", failed, len(repos))
+	}
+	return nil
+}
+
+// findRepo looks up a repository written as owner/name
+func findRepo(dbConn *db.DB, fullName string) (*db.Repository, error) {
+	owner, name, ok := strings.Cut(fullName, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("repositories are written as owner/name, not %q", fullName)
+	}
+	repo, err := dbConn.FindRepository(owner, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("no repository %s", fullName)
+		}
+		return nil, err
+	}
+	return repo, nil
+}
+
+// confirm asks a yes or no question on the terminal
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
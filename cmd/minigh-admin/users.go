@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"net/mail"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// minPasswordLength matches what registration accepts
+const minPasswordLength = 6
+
+func userCommand(dbConn *db.DB, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "create":
+		return createUser(dbConn, args[1:])
+	case "verify":
+		return updateUser(dbConn, args[1:], "verified", map[string]any{"is_verified": true})
+	case "disable":
+		return updateUser(dbConn, args[1:], "disabled", map[string]any{"disabled_at": time.Now()})
+	case "enable":
+		return updateUser(dbConn, args[1:], "enabled", map[string]any{"disabled_at": nil})
+	case "reset-password":
+		return resetPassword(dbConn, args[1:])
+	}
+	return errUsage
+}
+
+func createUser(dbConn *db.DB, args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := fs.String("username", "", "username")
+	email := fs.String("email", "", "email address")
+	password := fs.String("password", "", "password, generated when empty")
+	verified := fs.Bool("verified", false, "skip email verification")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+
+	if len(*username) < 3 {
+		return errors.New("username must be at least 3 characters")
+	}
+	if _, err := mail.ParseAddress(*email); err != nil {
+		return fmt.Errorf("invalid email address %q", *email)
+	}
+	var taken int64
+	if err := dbConn.Model(&db.User{}).Where("lower(username) = lower(?) OR email = ?", *username, *email).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errors.New("username or email already in use")
+	}
+
+	pass, generated, err := passwordOrRandom(*password)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user := db.User{Username: *username, Email: *email, Password: string(hash), IsVerified: *verified}
+	if err := dbConn.Create(&user).Error; err != nil {
+		return err
+	}
+	fmt.Printf("created user %s (id %d)\n", user.Username, user.ID)
+	if generated {
+		fmt.Printf("password: %s\n", pass)
+	}
+	return nil
+}
+
+// updateUser sets columns on the user named in args
+func updateUser(dbConn *db.DB, args []string, done string, columns map[string]any) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	user, err := findUser(dbConn, args[0])
+	if err != nil {
+		return err
+	}
+	if err := dbConn.Model(user).Updates(columns).Error; err != nil {
+		return err
+	}
+	fmt.Printf("%s %s\n", done, user.Username)
+	return nil
+}
+
+func resetPassword(dbConn *db.DB, args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	password := fs.String("password", "", "new password, generated when empty")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	user, err := findUser(dbConn, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	pass, generated, err := passwordOrRandom(*password)
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := dbConn.Model(user).Update("password", string(hash)).Error; err != nil {
+		return err
+	}
+
+	fmt.Printf("password of %s reset\n", user.Username)
+	if generated {
+		fmt.Printf("password: %s\n", pass)
+	}
+	return nil
+}
+
+func findUser(dbConn *db.DB, username string) (*db.User, error) {
+	user, err := dbConn.FindUser(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no user %s", username)
+	}
+	return user, err
+}
+
+// passwordOrRandom checks the given password, or generates one when it is empty
+func passwordOrRandom(password string) (string, bool, error) {
+	if password != "" {
+		if len(password) < minPasswordLength {
+			return "", false, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+		}
+		return password, false, nil
+	}
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
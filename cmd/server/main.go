@@ -1,35 +1,75 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"os"
+
 	"github.com/GordenArcher/mini-github/internal/config"
 	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/events"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/GordenArcher/mini-github/internal/jobs"
 	"github.com/GordenArcher/mini-github/internal/log"
 	"github.com/GordenArcher/mini-github/internal/mail"
+	"github.com/GordenArcher/mini-github/internal/maintenance"
 	"github.com/GordenArcher/mini-github/internal/middleware"
+	"github.com/GordenArcher/mini-github/internal/migrations"
+	"github.com/GordenArcher/mini-github/internal/policy"
+	"github.com/GordenArcher/mini-github/internal/protection"
 	"github.com/GordenArcher/mini-github/internal/redis"
 	"github.com/GordenArcher/mini-github/internal/routes"
+	"github.com/GordenArcher/mini-github/internal/secrets"
+	"github.com/GordenArcher/mini-github/internal/sshserver"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/GordenArcher/mini-github/internal/webhooks"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.uber.org/zap"
 )
 
 func main() {
 	godotenv.Load()
 
-	cfg := config.Load()
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flag.Parse()
 
 	log.Init()
 	defer log.Sync()
 
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Logger.Fatal("invalid configuration", zap.Error(err))
+	}
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
 	dbConn := db.Connect(cfg.DatabaseURL)
-	dbConn.AutoMigrate(&db.User{}, &db.Repository{})
+
+	if flag.Arg(0) == "migrate" {
+		if err := migrations.Command(dbConn, flag.Args()[1:], os.Stdout); err != nil {
+			log.Logger.Fatal("migration failed", zap.Error(err))
+		}
+		return
+	}
+	if err := migrations.Check(dbConn); err != nil {
+		log.Logger.Fatal("database is not ready", zap.Error(err))
+	}
 
 	redis.Connect(cfg.RedisAddr)
 
-	mailer := mail.New(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass)
+	storage.Use(storage.NewLocal(cfg.ReposPath))
+
+	transport, err := mail.NewTransport(cfg)
+	if err != nil {
+		log.Logger.Fatal("failed to set up mail transport", zap.Error(err))
+	}
+	mailer := mail.New(cfg.MailFrom, transport, mail.NewTemplates(cfg.MailTemplatesDir, cfg.PublicURL))
 
 	r := gin.Default()
 
-	r.Use(middleware.RateLimitMiddleware())
+	r.Use(middleware.RateLimitMiddleware(cfg.RateLimit, cfg.RateLimitPeriod))
 	api := r.Group("/api/v1")
 
 	// Auth API routes
@@ -37,7 +77,46 @@ func main() {
 
 	// Repo API routes
 	middleware.SetJWTSecret(cfg.JWTAccessSecret)
-	routes.RegisterRepoRoutes(api, dbConn)
+	middleware.SetDB(dbConn)
+	routes.RegisterRepoRoutes(api, dbConn, cfg)
+
+	// Account API routes
+	routes.RegisterUserRoutes(api, dbConn)
+
+	// Push checks, shared by HTTP and SSH
+	gitserver.RegisterPreReceiveHook(protection.Hook(dbConn))
+	gitserver.RegisterPreReceiveHook(policy.Hook(dbConn))
+	gitserver.RegisterPreReceiveHook(secrets.BlockHook(dbConn))
+	gitserver.RegisterPostReceiveHook(secrets.AlertHook(dbConn))
+	gitserver.RegisterPostReceiveHook(events.Hook(dbConn))
+	gitserver.RegisterPostReceiveHook(webhooks.PushHook(dbConn))
+
+	// Background jobs
+	jobs.Register(mail.JobSend, mailer.HandleSend)
+	jobs.Register(webhooks.JobDeliver, webhooks.HandleDeliver(dbConn))
+	if err := webhooks.QueuePending(context.Background(), dbConn); err != nil {
+		log.Logger.Error("failed to queue pending webhook deliveries", zap.Error(err))
+	}
+	jobs.Register(maintenance.JobRepository, maintenance.HandleRepository(dbConn))
+	jobs.Register(maintenance.JobSweep, maintenance.HandleSweep(dbConn))
+	jobs.Register(maintenance.JobPurge, maintenance.HandlePurge(dbConn, cfg.RepoRestoreWindow))
+	jobs.Every(maintenance.SweepInterval, jobs.QueueMaintenance, maintenance.JobSweep, nil)
+	jobs.Every(maintenance.PurgeInterval, jobs.QueueMaintenance, maintenance.JobPurge, nil)
+	go jobs.NewPool(cfg.JobWorkers, jobs.QueueMail, jobs.QueueWebhooks, jobs.QueueMaintenance).Run(context.Background())
+
+	// Git smart HTTP (clone, fetch, push)
+	routes.RegisterGitRoutes(r, dbConn, cfg)
+
+	// Git over SSH
+	sshServer, err := sshserver.New(dbConn, ":"+cfg.SSHPort, cfg.SSHHostKeyPath)
+	if err != nil {
+		log.Logger.Fatal("failed to set up ssh server", zap.Error(err))
+	}
+	go func() {
+		if err := sshServer.ListenAndServe(); err != nil {
+			log.Logger.Fatal("ssh server stopped", zap.Error(err))
+		}
+	}()
 
 	r.Run(":" + cfg.ServerPort)
 }
@@ -1,38 +1,310 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Environments
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
 )
 
+// Config holds the server settings. Every field can be set in the config file
+// under the name in its config tag, or in the environment variable with the
+// same name in upper case, which wins over the file.
 type Config struct {
-	DatabaseURL      string
-	JWTAccessSecret  string
-	JWTRefreshSecret string
-	SMTPHost         string
-	SMTPPort         string
-	SMTPUser         string
-	SMTPPass         string
-	RedisAddr        string
-	ServerPort       string
-}
-
-func Load() *Config {
+	Env string `config:"env"`
+
+	DatabaseURL string `config:"database_url"`
+	RedisAddr   string `config:"redis_addr"`
+
+	ServerPort string `config:"server_port"`
+	// PublicURL is where users reach the server, used in clone URLs and emails
+	PublicURL string `config:"public_url"`
+	// ReposPath is the directory bare repositories are stored under
+	ReposPath string `config:"repos_path"`
+	// RepoRestoreWindow is how long a deleted repository can be restored before it is purged
+	RepoRestoreWindow time.Duration `config:"repo_restore_window"`
+
+	JWTAccessSecret  string        `config:"jwt_access_secret"`
+	JWTRefreshSecret string        `config:"jwt_refresh_secret"`
+	AccessTokenTTL   time.Duration `config:"access_token_ttl"`
+	RefreshTokenTTL  time.Duration `config:"refresh_token_ttl"`
+
+	// RateLimit is how many API requests a client IP may make per RateLimitPeriod
+	RateLimit       int64         `config:"rate_limit"`
+	RateLimitPeriod time.Duration `config:"rate_limit_period"`
+
+	SSHPort        string `config:"ssh_port"`
+	SSHHostKeyPath string `config:"ssh_host_key_path"`
+
+	JobWorkers int `config:"job_workers"`
+
+	MailTransport    string `config:"mail_transport"`
+	MailFrom         string `config:"mail_from"`
+	MailDir          string `config:"mail_dir"`
+	MailTemplatesDir string `config:"mail_templates_dir"`
+	SMTPHost         string `config:"smtp_host"`
+	SMTPPort         string `config:"smtp_port"`
+	SMTPUser         string `config:"smtp_user"`
+	SMTPPass         string `config:"smtp_pass"`
+	SMTPTLS          string `config:"smtp_tls"`
+	SMTPPoolSize     int    `config:"smtp_pool_size"`
+}
+
+// secrets shipped in examples and defaults, refused in production
+var defaultSecrets = map[string]bool{
+	"dev_access_secret":      true,
+	"dev_refresh_secret":     true,
+	"replace_access_secret":  true,
+	"replace_refresh_secret": true,
+	"youraccesstokensecret":  true,
+	"yourrefreshtokensecret": true,
+}
+
+// minSecretLength is the shortest JWT secret accepted in production
+const minSecretLength = 32
+
+func defaults() *Config {
 	return &Config{
-		DatabaseURL:      getEnv("DATABASE_URL", "host=localhost user=postgres password=postgres dbname=mini_github port=5432 sslmode=disable TimeZone=UTC"),
-		JWTAccessSecret:  getEnv("JWT_ACCESS_SECRET", "dev_access_secret"),
-		JWTRefreshSecret: getEnv("JWT_REFRESH_SECRET", "dev_refresh_secret"),
-		SMTPHost:         getEnv("SMTP_HOST", ""),
-		SMTPPort:         getEnv("SMTP_PORT", "587"),
-		SMTPUser:         getEnv("SMTP_USER", ""),
-		SMTPPass:         getEnv("SMTP_PASS", ""),
-		RedisAddr:        getEnv("REDIS_ADDR", "localhost:6379"),
-		ServerPort:       getEnv("SERVER_PORT", "8080"),
+		Env:               EnvDevelopment,
+		DatabaseURL:       "host=localhost user=postgres password=postgres dbname=mini_github port=5432 sslmode=disable TimeZone=UTC",
+		RedisAddr:         "localhost:6379",
+		ServerPort:        "8080",
+		ReposPath:         "data/repos",
+		RepoRestoreWindow: 7 * 24 * time.Hour,
+		JWTAccessSecret:   "dev_access_secret",
+		JWTRefreshSecret:  "dev_refresh_secret",
+		AccessTokenTTL:    15 * time.Minute,
+		RefreshTokenTTL:   7 * 24 * time.Hour,
+		RateLimit:         10,
+		RateLimitPeriod:   time.Minute,
+		SSHPort:           "2222",
+		SSHHostKeyPath:    "data/ssh_host_ed25519_key",
+		JobWorkers:        4,
+		MailDir:           "data/mail",
+		SMTPPort:          "587",
+		SMTPTLS:           "starttls",
+		SMTPPoolSize:      2,
+	}
+}
+
+// Load reads the config file at path, if path isn't empty, applies
+// environment overrides and validates the result
+func Load(path string) (*Config, error) {
+	cfg := defaults()
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// defaults that follow from other settings
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	if cfg.PublicURL == "" {
+		cfg.PublicURL = "http://localhost:" + cfg.ServerPort
+	}
+	if cfg.MailTransport == "" {
+		// without an SMTP server, emails are only logged
+		cfg.MailTransport = "log"
+		if cfg.SMTPHost != "" {
+			cfg.MailTransport = "smtp"
+		}
+	}
+	if cfg.MailFrom == "" {
+		cfg.MailFrom = cfg.SMTPUser
+	}
+	// repository paths are stored in the database, so they mustn't depend on the working directory
+	if cfg.ReposPath != "" {
+		abs, err := filepath.Abs(cfg.ReposPath)
+		if err != nil {
+			return nil, fmt.Errorf("repos_path: %w", err)
+		}
+		cfg.ReposPath = abs
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Env != EnvDevelopment && c.Env != EnvProduction {
+		fail("env must be %s or %s", EnvDevelopment, EnvProduction)
+	}
+	for _, s := range []struct{ name, port string }{{"server_port", c.ServerPort}, {"ssh_port", c.SSHPort}, {"smtp_port", c.SMTPPort}} {
+		if p, err := strconv.Atoi(s.port); err != nil || p < 1 || p > 65535 {
+			fail("%s must be a port number", s.name)
+		}
+	}
+	if u, err := url.Parse(c.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail("public_url must be an http or https URL")
+	}
+	if c.DatabaseURL == "" {
+		fail("database_url is required")
+	}
+	if c.ReposPath == "" {
+		fail("repos_path is required")
+	}
+	if c.RepoRestoreWindow <= 0 {
+		fail("repo_restore_window must be positive")
+	}
+	if c.AccessTokenTTL <= 0 || c.RefreshTokenTTL <= 0 {
+		fail("token TTLs must be positive")
+	}
+	if c.RateLimit <= 0 || c.RateLimitPeriod <= 0 {
+		fail("rate_limit and rate_limit_period must be positive")
+	}
+	if c.JobWorkers <= 0 {
+		fail("job_workers must be positive")
+	}
+	if c.SMTPPoolSize <= 0 {
+		fail("smtp_pool_size must be positive")
+	}
+
+	switch c.MailTransport {
+	case "smtp":
+		if c.SMTPHost == "" {
+			fail("smtp_host is required for the smtp mail transport")
+		}
+	case "file":
+		if c.MailDir == "" {
+			fail("mail_dir is required for the file mail transport")
+		}
+	case "log":
+		// emails carry reset and verification links, which mustn't end up in production logs
+		if c.Env == EnvProduction {
+			fail("the log mail transport can't be used in production, set smtp_host or mail_transport")
+		}
+	default:
+		fail("mail_transport must be smtp, file or log")
+	}
+	switch c.SMTPTLS {
+	case "starttls", "tls", "none":
+	default:
+		fail("smtp_tls must be starttls, tls or none")
+	}
+
+	if c.JWTAccessSecret == "" || c.JWTRefreshSecret == "" {
+		fail("jwt_access_secret and jwt_refresh_secret are required")
+	}
+	if c.Env == EnvProduction {
+		for _, s := range []struct{ name, secret string }{{"jwt_access_secret", c.JWTAccessSecret}, {"jwt_refresh_secret", c.JWTRefreshSecret}} {
+			if defaultSecrets[s.secret] || len(s.secret) < minSecretLength {
+				fail("%s must be a random value of at least %d characters in production", s.name, minSecretLength)
+			}
+		}
+		if c.JWTAccessSecret == c.JWTRefreshSecret {
+			fail("jwt_access_secret and jwt_refresh_secret must differ")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsProduction reports whether the server runs in production mode
+func (c *Config) IsProduction() bool {
+	return c.Env == EnvProduction
+}
+
+// loadFile applies a YAML or TOML file, chosen by extension
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return errors.New("config file must be .yaml, .yml or .toml")
+	}
+	if err != nil {
+		return err
+	}
+
+	fields := c.fields()
+	for key, v := range values {
+		field, ok := fields[key]
+		if !ok {
+			return fmt.Errorf("unknown setting %s", key)
+		}
+		if err := set(field, fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for key, field := range c.fields() {
+		env := strings.ToUpper(key)
+		if v := os.Getenv(env); v != "" {
+			if err := set(field, v); err != nil {
+				return fmt.Errorf("%s: %w", env, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fields maps setting names to the fields they set
+func (c *Config) fields() map[string]reflect.Value {
+	v := reflect.ValueOf(c).Elem()
+	fields := map[string]reflect.Value{}
+	for i := 0; i < v.NumField(); i++ {
+		if key := v.Type().Field(i).Tag.Get("config"); key != "" {
+			fields[key] = v.Field(i)
+		}
 	}
+	return fields
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// set parses s into field according to its type
+func set(field reflect.Value, s string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(s)
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use a value like 15m or 24h", s)
+		}
+		field.SetInt(int64(d))
+	case int, int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		field.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
 	}
-	return fallback
+	return nil
 }
@@ -0,0 +1,40 @@
+package db
+
+import "path"
+
+// Matches reports whether the rule applies to branch. Patterns use path.Match
+// syntax, so * does not cross a slash: release/* covers release/1.0 but not
+// release/1.0/hotfix.
+func (p *BranchProtection) Matches(branch string) bool {
+	ok, err := path.Match(p.Pattern, branch)
+	return err == nil && ok
+}
+
+// AllowsPusher reports whether userID may push to branches the rule covers
+func (p *BranchProtection) AllowsPusher(userID uint) bool {
+	if !p.RestrictPushes {
+		return true
+	}
+	for _, id := range p.AllowedPusherIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// BranchProtections returns the rules of a repository that apply to branch
+func (d *DB) BranchProtections(repoID uint, branch string) ([]BranchProtection, error) {
+	var rules []BranchProtection
+	if err := d.Where("repository_id = ?", repoID).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	matching := rules[:0]
+	for _, r := range rules {
+		if r.Matches(branch) {
+			matching = append(matching, r)
+		}
+	}
+	return matching, nil
+}
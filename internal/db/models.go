@@ -10,28 +10,164 @@ import (
 )
 
 type User struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Username    string    `gorm:"uniqueIndex;not null" json:"username"`
-	Email       string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password    string    `gorm:"not null" json:"-"`
-	DisplayName string    `json:"display_name"`
-	AvatarURL   string    `json:"avatar_url"`
-	Bio         string    `json:"bio"`
-	IsVerified  bool      `gorm:"default:false" json:"is_verified"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Username    string     `gorm:"uniqueIndex;not null" json:"username"`
+	Email       string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password    string     `gorm:"not null" json:"-"`
+	DisplayName string     `json:"display_name"`
+	AvatarURL   string     `json:"avatar_url"`
+	Bio         string     `json:"bio"`
+	Locale      string     `json:"locale"` // language for emails, such as "en" or "pt-BR"
+	IsVerified  bool       `gorm:"default:false" json:"is_verified"`
+	DisabledAt  *time.Time `json:"-"` // set by an administrator, the user can't sign in or use Git
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type Repository struct {
-	ID          uint   `gorm:"primaryKey"`
-	Name        string `gorm:"not null"`
-	Description string
-	Visibility  string `gorm:"default:'private'"` // "private" or "public"
-	Path        string `gorm:"not null"`          // local path on server
-	OwnerID     uint
-	Owner       User `gorm:"foreignKey:OwnerID"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID            uint   `gorm:"primaryKey"`
+	Name          string `gorm:"not null"` // unique per owner, ignoring case
+	Description   string
+	Visibility    string `gorm:"default:'private'"`       // "private" or "public"
+	Path          string `gorm:"not null"`                // location in storage, such as 3/project.git
+	DefaultBranch string `gorm:"not null;default:'main'"` // HEAD points here
+	OwnerID       uint
+	Owner         User  `gorm:"foreignKey:OwnerID"`
+	ParentID      *uint `gorm:"index"` // the repository this one was forked from
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"` // deleted repositories can be restored until they are purged
+}
+
+// RepositoryRedirect keeps a repository reachable under an owner and name it
+// had before being renamed or transferred
+type RepositoryRedirect struct {
+	ID           uint   `gorm:"primaryKey"`
+	OwnerID      uint   `gorm:"not null"`
+	Name         string `gorm:"not null"`
+	RepositoryID uint   `gorm:"index;not null"`
+	CreatedAt    time.Time
+}
+
+// RepositoryTransfer is an owner's offer to give a repository to another
+// user. Nothing moves until the recipient accepts.
+type RepositoryTransfer struct {
+	ID           uint `gorm:"primaryKey"`
+	RepositoryID uint `gorm:"uniqueIndex;not null"` // one offer per repository at a time
+	Repository   Repository
+	FromID       uint `gorm:"not null"`
+	ToID         uint `gorm:"index;not null"`
+	CreatedAt    time.Time
+}
+
+type SSHKey struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"-"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+	Title       string     `gorm:"not null" json:"title"`
+	PublicKey   string     `gorm:"type:text;not null" json:"public_key"`
+	Fingerprint string     `gorm:"uniqueIndex;not null" json:"fingerprint"` // SHA256 fingerprint as printed by ssh-keygen -l
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index;not null" json:"-"`
+	User        User       `gorm:"foreignKey:UserID" json:"-"`
+	Name        string     `gorm:"not null" json:"name"`
+	TokenHash   string     `gorm:"uniqueIndex;not null" json:"-"` // sha256 of the token, the token itself is never stored
+	TokenPrefix string     `gorm:"not null" json:"token_prefix"`  // first characters, so users can tell tokens apart
+	Scopes      []string   `gorm:"serializer:json;type:text;not null" json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type BranchProtection struct {
+	ID                   uint      `gorm:"primaryKey" json:"id"`
+	RepositoryID         uint      `gorm:"uniqueIndex:idx_branch_protection_pattern;not null" json:"-"`
+	Pattern              string    `gorm:"uniqueIndex:idx_branch_protection_pattern;not null" json:"pattern"` // branch name or glob such as release/*
+	BlockForcePushes     bool      `json:"block_force_pushes"`
+	BlockDeletions       bool      `json:"block_deletions"`
+	RequireLinearHistory bool      `json:"require_linear_history"`
+	RestrictPushes       bool      `json:"restrict_pushes"` // only AllowedPusherIDs may push
+	AllowedPusherIDs     []uint    `gorm:"serializer:json;type:text" json:"-"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// RepositoryPolicy configures the pre-receive policies of a repository.
+// Zero values switch a policy off, except SecretScanning which defaults to alerts.
+type RepositoryPolicy struct {
+	ID                   uint      `gorm:"primaryKey" json:"-"`
+	RepositoryID         uint      `gorm:"uniqueIndex;not null" json:"-"`
+	MaxFileSize          int64     `json:"max_file_size"`                                    // bytes
+	ForbiddenPaths       []string  `gorm:"serializer:json;type:text" json:"forbidden_paths"` // globs such as .env or secrets/*
+	CommitMessagePattern string    `json:"commit_message_pattern"`                           // regular expression
+	AuthorEmailDomains   []string  `gorm:"serializer:json;type:text" json:"author_email_domains"`
+	SecretScanning       string    `json:"secret_scanning"` // "alert", "block" or "off"
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+type SecretAlert struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	RepositoryID    uint       `gorm:"uniqueIndex:idx_secret_alert;not null" json:"-"`
+	CommitSHA       string     `gorm:"uniqueIndex:idx_secret_alert;not null" json:"commit_sha"`
+	Path            string     `gorm:"uniqueIndex:idx_secret_alert;not null" json:"path"`
+	Line            int        `gorm:"uniqueIndex:idx_secret_alert;not null" json:"line"`
+	Rule            string     `gorm:"uniqueIndex:idx_secret_alert;not null" json:"rule"`
+	Ref             string     `json:"ref"`                                  // ref the commit was first pushed to
+	State           string     `gorm:"not null;default:'open'" json:"state"` // "open" or "dismissed"
+	DismissedReason string     `json:"dismissed_reason"`
+	DismissedByID   *uint      `json:"-"`
+	DismissedAt     *time.Time `json:"dismissed_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type PushEvent struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	RepositoryID uint       `gorm:"index;not null" json:"repository_id"`
+	Repository   Repository `gorm:"foreignKey:RepositoryID" json:"-"`
+	PusherID     uint       `gorm:"index;not null" json:"-"`
+	Pusher       User       `gorm:"foreignKey:PusherID" json:"-"`
+	Ref          string     `gorm:"not null" json:"ref"`
+	Before       string     `gorm:"not null" json:"before"` // all zeros when the push created the ref
+	After        string     `gorm:"not null" json:"after"`  // all zeros when the push deleted the ref
+	CommitCount  int        `json:"commit_count"`
+	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
+}
+
+type Webhook struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	OwnerID      uint      `gorm:"index;not null" json:"-"`
+	RepositoryID *uint     `gorm:"index" json:"repository_id"` // nil for hooks on all of the owner's repositories
+	URL          string    `gorm:"not null" json:"url"`
+	Secret       string    `json:"-"` // HMAC key for X-Hub-Signature-256
+	Events       []string  `gorm:"serializer:json;type:text;not null" json:"events"`
+	Active       bool      `gorm:"not null" json:"active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type WebhookDelivery struct {
+	ID              uint              `gorm:"primaryKey" json:"id"`
+	WebhookID       uint              `gorm:"index;not null" json:"webhook_id"`
+	GUID            string            `gorm:"uniqueIndex;not null" json:"guid"` // sent as X-MiniGitHub-Delivery
+	Event           string            `gorm:"not null" json:"event"`
+	RedeliveryOfID  *uint             `json:"redelivery_of"`
+	Status          string            `gorm:"index;not null" json:"status"` // "pending", "succeeded" or "failed"
+	Attempts        int               `json:"attempts"`
+	NextAttemptAt   *time.Time        `gorm:"index" json:"next_attempt_at"`
+	RequestHeaders  map[string]string `gorm:"serializer:json;type:text" json:"request_headers"`
+	RequestBody     string            `gorm:"type:text" json:"request_body"`
+	ResponseStatus  int               `json:"response_status"`
+	ResponseHeaders map[string]string `gorm:"serializer:json;type:text" json:"response_headers"`
+	ResponseBody    string            `gorm:"type:text" json:"response_body"`
+	Error           string            `json:"error"`
+	DurationMS      int64             `json:"duration_ms"`
+	DeliveredAt     *time.Time        `json:"delivered_at"`
+	CreatedAt       time.Time         `json:"created_at"`
 }
 
 func (u *User) AfterCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRepositoryNameLength is the longest repository name accepted
+const maxRepositoryNameLength = 100
+
+var repositoryNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// reservedRepositoryNames can't be used, in any case, because they read as
+// actions rather than repositories in URLs
+var reservedRepositoryNames = map[string]bool{
+	"api":      true,
+	"create":   true,
+	"new":      true,
+	"settings": true,
+}
+
+// ValidateRepositoryName checks a name for a new or renamed repository and
+// says what is wrong with it
+func ValidateRepositoryName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("repository name is required")
+	case len(name) > maxRepositoryNameLength:
+		return fmt.Errorf("repository name must be at most %d characters", maxRepositoryNameLength)
+	case !repositoryNamePattern.MatchString(name):
+		return errors.New("repository name may only contain letters, digits, '.', '-' and '_'")
+	case name == "." || name == "..":
+		return fmt.Errorf("%q is not a valid repository name", name)
+	case strings.HasSuffix(strings.ToLower(name), ".git"):
+		return errors.New("repository name must not end in .git")
+	case reservedRepositoryNames[strings.ToLower(name)]:
+		return fmt.Errorf("%q is a reserved name", name)
+	}
+	return nil
+}
+
+// FindRepository looks up a repository by its owner's username and its name,
+// ignoring case
+func (d *DB) FindRepository(owner, name string) (*Repository, error) {
+	user, err := d.FindUser(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var repo Repository
+	if err := d.Where("owner_id = ? AND lower(name) = lower(?)", user.ID, name).First(&repo).Error; err != nil {
+		return nil, err
+	}
+	repo.Owner = *user
+	return &repo, nil
+}
+
+// ResolveRepository is FindRepository that also follows the redirects left by
+// renames and transfers. moved reports whether the repository now has another
+// owner or name.
+func (d *DB) ResolveRepository(owner, name string) (repo *Repository, moved bool, err error) {
+	repo, err = d.FindRepository(owner, name)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return repo, false, err
+	}
+
+	user, err := d.FindUser(owner)
+	if err != nil {
+		return nil, false, err
+	}
+	var redirect RepositoryRedirect
+	if err := d.Where("owner_id = ? AND lower(name) = lower(?)", user.ID, name).First(&redirect).Error; err != nil {
+		return nil, false, err
+	}
+	var current Repository
+	if err := d.Preload("Owner").First(&current, redirect.RepositoryID).Error; err != nil {
+		return nil, false, err
+	}
+	return &current, true, nil
+}
+
+// FindUser looks up a user by username ignoring case, preferring an exact
+// match for accounts that predate case-insensitive usernames
+func (d *DB) FindUser(username string) (*User, error) {
+	var user User
+	err := d.Where("lower(username) = lower(?)", username).
+		Order(clause.OrderBy{Expression: clause.Expr{SQL: "username = ? DESC, id", Vars: []any{username}}}).
+		Take(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RepositoryNameTaken reports whether ownerID has a repository called name, ignoring case
+func (d *DB) RepositoryNameTaken(ownerID uint, name string) (bool, error) {
+	var count int64
+	err := d.Model(&Repository{}).Where("owner_id = ? AND lower(name) = lower(?)", ownerID, name).Count(&count).Error
+	return count > 0, err
+}
+
+// CreateRepository saves a new repository. A redirect left behind under its
+// owner and name by another repository is dropped, the new repository takes
+// the name over.
+func (d *DB) CreateRepository(repo *Repository) error {
+	return d.Transaction(func(tx *gorm.DB) error {
+		if err := deleteRedirect(tx, repo.OwnerID, repo.Name); err != nil {
+			return err
+		}
+		return tx.Create(repo).Error
+	})
+}
+
+// CanRead reports whether userID may read the repository. Zero means anonymous.
+func (r *Repository) CanRead(userID uint) bool {
+	return r.Visibility == "public" || (userID != 0 && r.OwnerID == userID)
+}
+
+// CanWrite reports whether userID may push to the repository
+func (r *Repository) CanWrite(userID uint) bool {
+	return userID != 0 && r.OwnerID == userID
+}
+
+// Forks lists the repositories forked from repoID, deleted ones included
+// since their Git data still borrows from it
+func (d *DB) Forks(repoID uint) ([]Repository, error) {
+	var forks []Repository
+	err := d.Unscoped().Where("parent_id = ?", repoID).Order("id").Find(&forks).Error
+	return forks, err
+}
+
+// DeleteRepository removes a repository and everything recorded about it.
+// Its forks stay, no longer pointing at it. The Git data itself is left to
+// the caller.
+func (d *DB) DeleteRepository(repo *Repository) error {
+	return d.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&Repository{}).Where("parent_id = ?", repo.ID).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		hooks := tx.Model(&Webhook{}).Select("id").Where("repository_id = ?", repo.ID)
+		if err := tx.Where("webhook_id IN (?)", hooks).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []any{&Webhook{}, &BranchProtection{}, &RepositoryPolicy{}, &SecretAlert{}, &PushEvent{}, &RepositoryRedirect{}, &RepositoryTransfer{}} {
+			if err := tx.Where("repository_id = ?", repo.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&Repository{}, repo.ID).Error
+	})
+}
+
+// SoftDeleteRepository marks a repository deleted, recording where its Git
+// data is kept until it is restored or purged
+func (d *DB) SoftDeleteRepository(repo *Repository, path string) error {
+	now := time.Now()
+	if err := d.Model(repo).Updates(map[string]any{"path": path, "deleted_at": now}).Error; err != nil {
+		return err
+	}
+	repo.Path, repo.DeletedAt = path, gorm.DeletedAt{Time: now, Valid: true}
+	return nil
+}
+
+// FindDeletedRepository returns the repository of ownerID called name that
+// was deleted last
+func (d *DB) FindDeletedRepository(ownerID uint, name string) (*Repository, error) {
+	var repo Repository
+	err := d.Unscoped().Preload("Owner").
+		Where("owner_id = ? AND lower(name) = lower(?) AND deleted_at IS NOT NULL", ownerID, name).
+		Order("deleted_at DESC").First(&repo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// RestoreRepository undeletes a repository whose Git data is back at path
+func (d *DB) RestoreRepository(repo *Repository, path string) error {
+	if err := d.Unscoped().Model(repo).Updates(map[string]any{"path": path, "deleted_at": nil}).Error; err != nil {
+		return err
+	}
+	repo.Path, repo.DeletedAt = path, gorm.DeletedAt{}
+	return nil
+}
+
+// DeletedRepositories lists repositories deleted before t, oldest first
+func (d *DB) DeletedRepositories(before time.Time) ([]Repository, error) {
+	var repos []Repository
+	err := d.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Order("deleted_at").Find(&repos).Error
+	return repos, err
+}
+
+// MoveRepository gives a repository a new owner, name or both, recording its
+// new location in storage. The old owner and name redirect to it. Its
+// webhooks move along; the previous owner's account-wide webhooks stop
+// covering it.
+func (d *DB) MoveRepository(repo *Repository, to *User, name, path string) error {
+	err := d.Transaction(func(tx *gorm.DB) error {
+		// the repository may be moving back to a name it had before
+		if err := deleteRedirect(tx, to.ID, name); err != nil {
+			return err
+		}
+		// a change of case alone needs no redirect, lookups ignore case
+		if to.ID != repo.OwnerID || !strings.EqualFold(name, repo.Name) {
+			if err := deleteRedirect(tx, repo.OwnerID, repo.Name); err != nil {
+				return err
+			}
+			if err := tx.Create(&RepositoryRedirect{OwnerID: repo.OwnerID, Name: repo.Name, RepositoryID: repo.ID}).Error; err != nil {
+				return err
+			}
+		}
+
+		if to.ID != repo.OwnerID {
+			if err := tx.Model(&Webhook{}).Where("repository_id = ?", repo.ID).Update("owner_id", to.ID).Error; err != nil {
+				return err
+			}
+			// whatever the previous owner offered is void
+			if err := tx.Where("repository_id = ?", repo.ID).Delete(&RepositoryTransfer{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(repo).Updates(map[string]any{"owner_id": to.ID, "name": name, "path": path}).Error
+	})
+	if err != nil {
+		return err
+	}
+	repo.OwnerID, repo.Owner, repo.Name, repo.Path = to.ID, *to, name, path
+	return nil
+}
+
+// OfferTransfer records that the owner of repo offers it to to, replacing
+// any earlier offer
+func (d *DB) OfferTransfer(repo *Repository, to *User) (*RepositoryTransfer, error) {
+	offer := RepositoryTransfer{RepositoryID: repo.ID, FromID: repo.OwnerID, ToID: to.ID}
+	err := d.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("repository_id = ?", repo.ID).Delete(&RepositoryTransfer{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&offer).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &offer, nil
+}
+
+func deleteRedirect(tx *gorm.DB, ownerID uint, name string) error {
+	return tx.Where("owner_id = ? AND lower(name) = lower(?)", ownerID, name).Delete(&RepositoryRedirect{}).Error
+}
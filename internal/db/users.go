@@ -0,0 +1,16 @@
+package db
+
+// Disabled reports whether an administrator has disabled the account
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// UserDisabled reports whether the user with the given ID is disabled.
+// A user that no longer exists counts as disabled.
+func (d *DB) UserDisabled(id uint) (bool, error) {
+	var users []User
+	if err := d.Select("id", "disabled_at").Where("id = ?", id).Limit(1).Find(&users).Error; err != nil {
+		return false, err
+	}
+	return len(users) == 0 || users[0].Disabled(), nil
+}
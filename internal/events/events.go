@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"gorm.io/gorm"
+)
+
+// Hook records a push event for each ref a push changed and marks the
+// repository as updated
+func Hook(dbConn *db.DB) gitserver.PostReceiveHook {
+	return gitserver.PostReceiveFunc(func(ctx context.Context, p *gitserver.Push) error {
+		events := make([]db.PushEvent, 0, len(p.Updates))
+		for _, u := range p.Updates {
+			commits, err := p.NewCommits(u)
+			if err != nil {
+				return err
+			}
+			events = append(events, db.PushEvent{
+				RepositoryID: p.Repo.ID,
+				PusherID:     p.PusherID,
+				Ref:          u.Name.String(),
+				Before:       u.Old.String(),
+				After:        u.New.String(),
+				CommitCount:  len(commits),
+			})
+		}
+
+		return dbConn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&events).Error; err != nil {
+				return err
+			}
+			return tx.Model(&db.Repository{}).Where("id = ?", p.Repo.ID).Update("updated_at", time.Now()).Error
+		})
+	})
+}
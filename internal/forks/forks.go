@@ -0,0 +1,55 @@
+package forks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/storage"
+)
+
+// Forks don't copy their parent's objects, they borrow them through Git
+// alternates. The functions here keep them working when the parent's Git
+// data moves or goes away.
+
+// Relink points the forks of repo at where its Git data is stored now. Each
+// fork lists everything it borrows, so forks of forks are relinked too.
+func Relink(dbConn *db.DB, repo *db.Repository) error {
+	forks, err := dbConn.Forks(repo.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, fork := range forks {
+		if err := storage.Borrow(fork.Path, repo.Path); err != nil {
+			errs = append(errs, fmt.Errorf("fork %d: %w", fork.ID, err))
+			continue
+		}
+		if err := Relink(dbConn, &fork); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Detach copies what the forks of repo borrow from it into the forks
+// themselves, so the Git data of repo can be removed. Forks of those forks
+// borrow from repo directly as well and are detached too.
+func Detach(ctx context.Context, dbConn *db.DB, repo *db.Repository) error {
+	forks, err := dbConn.Forks(repo.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, fork := range forks {
+		if err := storage.Detach(ctx, fork.Path); err != nil {
+			return fmt.Errorf("fork %d: %w", fork.ID, err)
+		}
+		if err := Detach(ctx, dbConn, &fork); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package gitserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Services understood by the smart protocol
+const (
+	UploadPack  = "git-upload-pack"
+	ReceivePack = "git-receive-pack"
+)
+
+// IsValidService reports whether service is one we know how to run
+func IsValidService(service string) bool {
+	return service == UploadPack || service == ReceivePack
+}
+
+// IsProtocolV2 reports whether the client asked for wire protocol version 2
+func IsProtocolV2(protocol string) bool {
+	for _, p := range strings.Split(protocol, ":") {
+		if p == "version=2" {
+			return true
+		}
+	}
+	return false
+}
+
+// AdvertiseRefs writes the smart HTTP ref advertisement for service.
+// protocol is the value of the client's Git-Protocol header.
+func AdvertiseRefs(ctx context.Context, service, repoPath, protocol string, w io.Writer) error {
+	if !IsValidService(service) {
+		return fmt.Errorf("unsupported service %q", service)
+	}
+
+	// v2 clients expect the capability advertisement without the service banner
+	if !IsProtocolV2(protocol) {
+		if err := WritePacket(w, "# service="+service+"\n"); err != nil {
+			return err
+		}
+		if err := WriteFlush(w); err != nil {
+			return err
+		}
+	}
+
+	cmd := gitCommand(ctx, service, protocol, "--stateless-rpc", "--advertise-refs", repoPath)
+	cmd.Stdout = w
+	return run(cmd)
+}
+
+// ServeRPC runs one stateless request/response round of service against repoPath
+func ServeRPC(ctx context.Context, service, repoPath, protocol string, in io.Reader, out io.Writer) error {
+	if !IsValidService(service) {
+		return fmt.Errorf("unsupported service %q", service)
+	}
+
+	cmd := gitCommand(ctx, service, protocol, "--stateless-rpc", repoPath)
+	cmd.Stdin = in
+	cmd.Stdout = out
+	return run(cmd)
+}
+
+// ServeSession runs service as a long-lived session, as used by the SSH transport
+func ServeSession(ctx context.Context, service, repoPath, protocol string, in io.Reader, out, errOut io.Writer) error {
+	if !IsValidService(service) {
+		return fmt.Errorf("unsupported service %q", service)
+	}
+
+	cmd := gitCommand(ctx, service, protocol, repoPath)
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+
+	// clients may keep their side open after the exchange, so don't make
+	// Wait block on copying stdin
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer stdin.Close()
+		_, _ = io.Copy(stdin, in)
+	}()
+
+	return cmd.Run()
+}
+
+// WritePacket writes s as a single pkt-line
+func WritePacket(w io.Writer, s string) error {
+	_, err := fmt.Fprintf(w, "%04x%s", len(s)+4, s)
+	return err
+}
+
+// WriteFlush writes a flush-pkt
+func WriteFlush(w io.Writer) error {
+	_, err := io.WriteString(w, "0000")
+	return err
+}
+
+// ReadPacket reads one pkt-line from r and also appends its raw bytes to raw.
+// flush is true for a flush-pkt.
+func ReadPacket(r io.Reader, raw *bytes.Buffer) (payload []byte, flush bool, err error) {
+	var head [4]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return nil, false, err
+	}
+	raw.Write(head[:])
+
+	n, err := strconv.ParseUint(string(head[:]), 16, 16)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid pkt-line length %q", head)
+	}
+	if n == 0 {
+		return nil, true, nil
+	}
+	if n < 4 {
+		return nil, false, fmt.Errorf("invalid pkt-line length %q", head)
+	}
+
+	payload = make([]byte, n-4)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, false, err
+	}
+	raw.Write(payload)
+	return payload, false, nil
+}
+
+func gitCommand(ctx context.Context, service, protocol string, args ...string) *exec.Cmd {
+	// "git-upload-pack" -> "git upload-pack"
+	args = append([]string{strings.TrimPrefix(service, "git-")}, args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = os.Environ()
+	if protocol != "" {
+		cmd.Env = append(cmd.Env, "GIT_PROTOCOL="+protocol)
+	}
+	return cmd
+}
+
+func run(cmd *exec.Cmd) error {
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w: %s", strings.Join(cmd.Args[:2], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
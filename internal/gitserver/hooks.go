@@ -0,0 +1,182 @@
+package gitserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// RefUpdate is one ref change requested by a push
+type RefUpdate struct {
+	Name plumbing.ReferenceName
+	Old  plumbing.Hash
+	New  plumbing.Hash
+}
+
+// IsCreate reports whether the push creates the ref
+func (u RefUpdate) IsCreate() bool { return u.Old.IsZero() }
+
+// IsDelete reports whether the push deletes the ref
+func (u RefUpdate) IsDelete() bool { return u.New.IsZero() }
+
+// Push is a push waiting for the pre-receive hooks. Git sees the
+// repository's objects plus the pushed ones, which stay quarantined
+// until every hook has accepted the push.
+type Push struct {
+	Repo     *db.Repository
+	PusherID uint
+	Git      *git.Repository
+	Updates  []RefUpdate
+
+	// tips of the refs the repository had before the push
+	existing []plumbing.Hash
+}
+
+// NewCommits returns the commits u brings into the repository, newest first:
+// those reachable from u.New but not from u.Old, or, for a new ref, not from
+// any ref the repository already has.
+func (p *Push) NewCommits(u RefUpdate) ([]*object.Commit, error) {
+	if u.IsDelete() {
+		return nil, nil
+	}
+	tip, err := gitutil.PeelToCommit(p.Git, u.New)
+	if err != nil {
+		// tags may point at trees and blobs, which bring no commits
+		return nil, nil
+	}
+
+	exclude := p.existing
+	if !u.IsCreate() {
+		exclude = []plumbing.Hash{u.Old}
+	}
+	var excluded []*object.Commit
+	for _, h := range exclude {
+		if c, err := gitutil.PeelToCommit(p.Git, h); err == nil {
+			excluded = append(excluded, c)
+		}
+	}
+	return gitutil.RevList(tip, excluded...)
+}
+
+// IsFastForward reports whether u only adds commits on top of the old tip.
+// Creations and deletions are not fast-forwards.
+func (p *Push) IsFastForward(u RefUpdate) (bool, error) {
+	if u.IsCreate() || u.IsDelete() {
+		return false, nil
+	}
+	oldCommit, err := p.Git.CommitObject(u.Old)
+	if err != nil {
+		return false, err
+	}
+	newCommit, err := p.Git.CommitObject(u.New)
+	if err != nil {
+		return false, err
+	}
+	return oldCommit.IsAncestor(newCommit)
+}
+
+// Rejection refuses a push. Hooks return it, joined with errors.Join when
+// several refs are refused, and the pusher sees Reason next to the ref.
+type Rejection struct {
+	Ref    plumbing.ReferenceName // empty refuses the whole push
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	if r.Ref == "" {
+		return r.Reason
+	}
+	return fmt.Sprintf("%s: %s", r.Ref, r.Reason)
+}
+
+// PreReceiveHook inspects a push before any ref is updated. Returning a
+// Rejection refuses the push; any other error refuses it as an internal failure.
+type PreReceiveHook interface {
+	PreReceive(ctx context.Context, p *Push) error
+}
+
+// PreReceiveFunc adapts a function to PreReceiveHook
+type PreReceiveFunc func(ctx context.Context, p *Push) error
+
+func (f PreReceiveFunc) PreReceive(ctx context.Context, p *Push) error { return f(ctx, p) }
+
+// PostReceiveHook is told about a push once its refs are updated. Updates
+// only lists the refs that changed. Errors are reported but the push stands.
+type PostReceiveHook interface {
+	PostReceive(ctx context.Context, p *Push) error
+}
+
+// PostReceiveFunc adapts a function to PostReceiveHook
+type PostReceiveFunc func(ctx context.Context, p *Push) error
+
+func (f PostReceiveFunc) PostReceive(ctx context.Context, p *Push) error { return f(ctx, p) }
+
+var (
+	preReceiveHooks  []PreReceiveHook
+	postReceiveHooks []PostReceiveHook
+)
+
+// RegisterPreReceiveHook adds a hook run on every push, in registration order.
+// Hooks are registered during startup, before the server accepts pushes.
+func RegisterPreReceiveHook(h PreReceiveHook) {
+	preReceiveHooks = append(preReceiveHooks, h)
+}
+
+// RegisterPostReceiveHook adds a hook run after every push, in registration order
+func RegisterPostReceiveHook(h PostReceiveHook) {
+	postReceiveHooks = append(postReceiveHooks, h)
+}
+
+// runPostReceive runs every hook, whatever the others return
+func runPostReceive(ctx context.Context, p *Push) error {
+	var errs []error
+	for _, h := range postReceiveHooks {
+		if err := h.PostReceive(ctx, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runPreReceive runs every hook and collects their rejections
+func runPreReceive(ctx context.Context, p *Push) ([]*Rejection, error) {
+	var rejected []*Rejection
+	for _, h := range preReceiveHooks {
+		err := h.PreReceive(ctx, p)
+		if err == nil {
+			continue
+		}
+		r, ok := rejections(err)
+		if !ok {
+			return nil, err
+		}
+		rejected = append(rejected, r...)
+	}
+	return rejected, nil
+}
+
+// rejections unpacks err into rejections. ok is false if err holds anything else.
+func rejections(err error) (rejected []*Rejection, ok bool) {
+	if joined, isJoined := err.(interface{ Unwrap() []error }); isJoined {
+		for _, e := range joined.Unwrap() {
+			r, ok := rejections(e)
+			if !ok {
+				return nil, false
+			}
+			rejected = append(rejected, r...)
+		}
+		return rejected, true
+	}
+
+	var r *Rejection
+	if errors.As(err, &r) {
+		return []*Rejection{r}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,405 @@
+package gitserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// sideband packet payload limits, after the 4 byte length and the band byte
+const (
+	sidebandMax   = 1000 - 5
+	sideband64Max = 65520 - 5
+)
+
+// ServeReceivePack runs one stateless receive-pack round for a push to repo.
+// The pushed objects are unpacked into a quarantine and the pre-receive
+// hooks run before git-receive-pack sees the push, so a rejected push
+// never touches the repository. The pusher gets a report naming the
+// refused refs. Once refs are updated the post-receive hooks run.
+func ServeReceivePack(ctx context.Context, repo *db.Repository, pusherID uint, protocol string, in io.Reader, out io.Writer) error {
+	repoPath := storage.Path(repo.Path)
+	req, err := readPushRequest(in)
+	if err != nil {
+		return err
+	}
+	if len(req.updates) == 0 {
+		return ServeRPC(ctx, ReceivePack, repoPath, protocol, bytes.NewReader(req.raw), out)
+	}
+
+	q, err := newQuarantine(ctx, repoPath)
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+
+	if req.hasPack() {
+		if err := q.indexPack(ctx, in); err != nil {
+			_ = req.writeReport(out, "index-pack failed", nil, "unpacker error")
+			return err
+		}
+	}
+
+	push, err := q.push(repo, pusherID, req.updates)
+	if err != nil {
+		return err
+	}
+	rejected, err := runPreReceive(ctx, push)
+	if err != nil {
+		_ = req.writeReport(out, "ok", nil, "pre-receive hook failed")
+		return fmt.Errorf("pre-receive hook: %w", err)
+	}
+	if len(rejected) > 0 {
+		return req.writeReport(out, "ok", rejected, "")
+	}
+
+	replay := io.Reader(bytes.NewReader(req.raw))
+	if q.pack != nil {
+		replay = io.MultiReader(replay, q.pack)
+	}
+	if err := ServeRPC(ctx, ReceivePack, repoPath, protocol, replay, out); err != nil {
+		return err
+	}
+
+	// git-receive-pack may still have refused some updates itself
+	if push.Updates, err = appliedUpdates(repoPath, push.Updates); err != nil {
+		return err
+	}
+	if len(push.Updates) == 0 {
+		return nil
+	}
+	if err := runPostReceive(ctx, push); err != nil {
+		return fmt.Errorf("post-receive hook: %w", err)
+	}
+	return nil
+}
+
+// appliedUpdates returns the updates the repository's refs now reflect
+func appliedUpdates(repoPath string, updates []RefUpdate) ([]RefUpdate, error) {
+	r, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []RefUpdate
+	for _, u := range updates {
+		ref, err := r.Reference(u.Name, false)
+		switch {
+		case errors.Is(err, plumbing.ErrReferenceNotFound):
+			if u.IsDelete() {
+				applied = append(applied, u)
+			}
+		case err != nil:
+			return nil, err
+		case ref.Hash() == u.New:
+			applied = append(applied, u)
+		}
+	}
+	return applied, nil
+}
+
+// ServeReceivePackSession is ServeReceivePack for the SSH transport, which
+// expects the ref advertisement on the same stream
+func ServeReceivePackSession(ctx context.Context, repo *db.Repository, pusherID uint, protocol string, in io.Reader, out io.Writer) error {
+	cmd := gitCommand(ctx, ReceivePack, protocol, "--stateless-rpc", "--advertise-refs", storage.Path(repo.Path))
+	cmd.Stdout = out
+	if err := run(cmd); err != nil {
+		return err
+	}
+
+	// a client with nothing to push hangs up after the advertisement
+	err := ServeReceivePack(ctx, repo, pusherID, protocol, in, out)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// pushRequest is the part of a push before the pack: shallow lines,
+// ref update commands and push options, kept verbatim for replay
+type pushRequest struct {
+	raw          []byte
+	updates      []RefUpdate
+	capabilities []string
+}
+
+func readPushRequest(r io.Reader) (*pushRequest, error) {
+	var raw bytes.Buffer
+	req := &pushRequest{}
+
+	for {
+		payload, flush, err := ReadPacket(r, &raw)
+		if err != nil {
+			return nil, err
+		}
+		if flush {
+			break
+		}
+
+		line := strings.TrimSuffix(string(payload), "\n")
+		if strings.HasPrefix(line, "shallow ") {
+			continue
+		}
+		// the first command carries the client's capabilities after a NUL
+		if cmd, caps, ok := strings.Cut(line, "\x00"); ok {
+			line = cmd
+			req.capabilities = strings.Fields(caps)
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid push command %q", line)
+		}
+		req.updates = append(req.updates, RefUpdate{
+			Old:  plumbing.NewHash(fields[0]),
+			New:  plumbing.NewHash(fields[1]),
+			Name: plumbing.ReferenceName(fields[2]),
+		})
+	}
+
+	if req.has("push-options") {
+		for {
+			_, flush, err := ReadPacket(r, &raw)
+			if err != nil {
+				return nil, err
+			}
+			if flush {
+				break
+			}
+		}
+	}
+
+	req.raw = raw.Bytes()
+	return req, nil
+}
+
+func (req *pushRequest) has(capability string) bool {
+	for _, c := range req.capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPack reports whether a pack follows the commands. Pushes that only
+// delete refs send none.
+func (req *pushRequest) hasPack() bool {
+	for _, u := range req.updates {
+		if !u.IsDelete() {
+			return true
+		}
+	}
+	return false
+}
+
+// writeReport answers the push with report-status, refusing every update.
+// Refs without a rejection of their own are refused with fallback, or
+// because the rest of the push was.
+func (req *pushRequest) writeReport(w io.Writer, unpack string, rejected []*Rejection, fallback string) error {
+	if !req.has("report-status") && !req.has("report-status-v2") {
+		return nil
+	}
+
+	reasons := map[plumbing.ReferenceName]string{}
+	whole := ""
+	for _, r := range rejected {
+		if r.Ref == "" {
+			whole = r.Reason
+		} else if _, seen := reasons[r.Ref]; !seen {
+			reasons[r.Ref] = r.Reason
+		}
+	}
+
+	var report bytes.Buffer
+	if err := WritePacket(&report, "unpack "+unpack+"\n"); err != nil {
+		return err
+	}
+	for _, u := range req.updates {
+		reason, ok := reasons[u.Name]
+		switch {
+		case ok:
+		case whole != "":
+			reason = whole
+		case fallback != "":
+			reason = fallback
+		default:
+			reason = "another ref in this push was refused"
+		}
+		// a reason spans exactly one pkt-line
+		reason = strings.Join(strings.Fields(reason), " ")
+		if err := WritePacket(&report, "ng "+u.Name.String()+" "+reason+"\n"); err != nil {
+			return err
+		}
+	}
+	if err := WriteFlush(&report); err != nil {
+		return err
+	}
+
+	max := 0
+	switch {
+	case req.has("side-band-64k"):
+		max = sideband64Max
+	case req.has("side-band"):
+		max = sidebandMax
+	default:
+		_, err := w.Write(report.Bytes())
+		return err
+	}
+
+	data := report.Bytes()
+	for len(data) > 0 {
+		n := min(len(data), max)
+		if err := WritePacket(w, "\x01"+string(data[:n])); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return WriteFlush(w)
+}
+
+// quarantine is a throwaway bare repository borrowing the real one's objects
+// through alternates. Pushed objects land here until the hooks accept them.
+type quarantine struct {
+	dir      string
+	repoPath string
+	pack     *os.File // the pack as received, replayed to git-receive-pack
+}
+
+func newQuarantine(ctx context.Context, repoPath string) (*quarantine, error) {
+	repoPath, err := filepath.Abs(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "mini-github-push-")
+	if err != nil {
+		return nil, err
+	}
+	q := &quarantine{dir: dir, repoPath: repoPath}
+
+	if err := run(exec.CommandContext(ctx, "git", "init", "--bare", "-q", dir)); err != nil {
+		q.Close()
+		return nil, err
+	}
+	// a fork borrows its parent's objects, and go-git doesn't follow the
+	// alternates of an alternate, so the quarantine borrows them directly too
+	objects := filepath.Join(repoPath, "objects")
+	list := []byte(objects + "\n")
+	if inherited, err := os.ReadFile(filepath.Join(objects, "info", "alternates")); err == nil {
+		list = append(list, inherited...)
+	}
+	alternates := filepath.Join(dir, "objects", "info", "alternates")
+	if err := os.WriteFile(alternates, list, 0644); err != nil {
+		q.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// indexPack unpacks the pack read from in into the quarantine, spooling it
+// to disk on the way for the replay
+func (q *quarantine) indexPack(ctx context.Context, in io.Reader) error {
+	f, err := os.Create(filepath.Join(q.dir, "incoming.pack"))
+	if err != nil {
+		return err
+	}
+	q.pack = f
+	spool := &spoolWriter{w: f}
+
+	cmd := exec.CommandContext(ctx, "git", "--git-dir", q.dir, "index-pack", "--stdin", "--fix-thin")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	// index-pack exits at the end of the pack, which on SSH is not the end of in
+	go func() {
+		defer stdin.Close()
+		_, _ = io.Copy(stdin, io.TeeReader(in, spool))
+	}()
+
+	err = run(cmd)
+	spool.stop()
+	if err != nil {
+		return err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// push opens the quarantine and describes the push for the hooks
+func (q *quarantine) push(repo *db.Repository, pusherID uint, updates []RefUpdate) (*Push, error) {
+	storage := filesystem.NewStorageWithOptions(osfs.New(q.dir), cache.NewObjectLRUDefault(), filesystem.Options{
+		// alternates holds an absolute path
+		AlternatesFS: osfs.New("/"),
+	})
+	r, err := git.Open(storage, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := git.PlainOpen(q.repoPath)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := current.References()
+	if err != nil {
+		return nil, err
+	}
+	var existing []plumbing.Hash
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() == plumbing.HashReference {
+			existing = append(existing, ref.Hash())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Push{Repo: repo, PusherID: pusherID, Git: r, Updates: updates, existing: existing}, nil
+}
+
+func (q *quarantine) Close() error {
+	if q.pack != nil {
+		q.pack.Close()
+	}
+	return os.RemoveAll(q.dir)
+}
+
+// spoolWriter stops taking writes once the pack has been read, so a copy
+// still blocked on the client can't write into the spool during the replay
+type spoolWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	stopped bool
+}
+
+func (s *spoolWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return 0, io.ErrClosedPipe
+	}
+	return s.w.Write(p)
+}
+
+func (s *spoolWriter) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+}
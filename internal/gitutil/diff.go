@@ -0,0 +1,260 @@
+package gitutil
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// DiffContext is the number of unchanged lines shown around each change
+const DiffContext = 3
+
+// Line types in a hunk
+const (
+	LineContext  = "context"
+	LineAddition = "addition"
+	LineDeletion = "deletion"
+)
+
+// File statuses
+const (
+	StatusAdded    = "added"
+	StatusModified = "modified"
+	StatusDeleted  = "deleted"
+	StatusRenamed  = "renamed"
+)
+
+// DiffLine is one line of a hunk. Line numbers are 1-based and omitted on the
+// side the line doesn't exist in.
+type DiffLine struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// Hunk is a run of changes with surrounding context
+type Hunk struct {
+	Header   string     `json:"header"`
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// FileDiff describes the changes to one file
+type FileDiff struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Status    string `json:"status"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary"`
+	Hunks     []Hunk `json:"hunks"`
+}
+
+// Diff is the structured form of a patch
+type Diff struct {
+	ChangedFiles int        `json:"changed_files"`
+	Additions    int        `json:"additions"`
+	Deletions    int        `json:"deletions"`
+	Files        []FileDiff `json:"files"`
+}
+
+// TreePatch diffs two trees with rename detection. Either may be nil for an empty tree.
+func TreePatch(ctx context.Context, from, to *object.Tree) (*object.Patch, error) {
+	changes, err := object.DiffTreeWithOptions(ctx, from, to, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, err
+	}
+	return changes.PatchContext(ctx)
+}
+
+// CommitPatch diffs a commit against its first parent, or the empty tree for a root commit
+func CommitPatch(ctx context.Context, c *object.Commit) (*object.Patch, error) {
+	to, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var from *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if from, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	return TreePatch(ctx, from, to)
+}
+
+// NewDiff converts a go-git patch into hunks with line numbers
+func NewDiff(p *object.Patch) Diff {
+	d := Diff{Files: []FileDiff{}}
+	for _, fp := range p.FilePatches() {
+		f := newFileDiff(fp)
+		d.ChangedFiles++
+		d.Additions += f.Additions
+		d.Deletions += f.Deletions
+		d.Files = append(d.Files, f)
+	}
+	return d
+}
+
+func newFileDiff(fp fdiff.FilePatch) FileDiff {
+	from, to := fp.Files()
+
+	f := FileDiff{Binary: fp.IsBinary(), Hunks: []Hunk{}}
+	switch {
+	case from == nil:
+		f.Status, f.Path = StatusAdded, to.Path()
+	case to == nil:
+		f.Status, f.Path = StatusDeleted, from.Path()
+	case from.Path() != to.Path():
+		f.Status, f.Path, f.OldPath = StatusRenamed, to.Path(), from.Path()
+	default:
+		f.Status, f.Path = StatusModified, to.Path()
+	}
+	if f.Binary {
+		return f
+	}
+
+	lines := numberLines(fp.Chunks())
+	for _, l := range lines {
+		switch l.Type {
+		case LineAddition:
+			f.Additions++
+		case LineDeletion:
+			f.Deletions++
+		}
+	}
+	f.Hunks = buildHunks(lines, DiffContext)
+	return f
+}
+
+// numberedLine is a diff line with the position it occupies on both sides
+type numberedLine struct {
+	DiffLine
+	oldPos, newPos int
+}
+
+func numberLines(chunks []fdiff.Chunk) []numberedLine {
+	var lines []numberedLine
+	oldNo, newNo := 1, 1
+	for _, chunk := range chunks {
+		content := strings.TrimSuffix(chunk.Content(), "\n")
+		if chunk.Content() == "" {
+			continue
+		}
+		for _, text := range strings.Split(content, "\n") {
+			l := numberedLine{DiffLine: DiffLine{Content: text}, oldPos: oldNo, newPos: newNo}
+			switch chunk.Type() {
+			case fdiff.Equal:
+				l.Type, l.OldLine, l.NewLine = LineContext, oldNo, newNo
+				oldNo++
+				newNo++
+			case fdiff.Delete:
+				l.Type, l.OldLine = LineDeletion, oldNo
+				oldNo++
+			case fdiff.Add:
+				l.Type, l.NewLine = LineAddition, newNo
+				newNo++
+			}
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// buildHunks groups changed lines with up to context unchanged lines around
+// them, merging groups whose context would touch, like diff -U.
+func buildHunks(lines []numberedLine, context int) []Hunk {
+	hunks := []Hunk{}
+	n := len(lines)
+	for i := 0; i < n; {
+		first := i
+		for first < n && lines[first].Type == LineContext {
+			first++
+		}
+		if first == n {
+			break
+		}
+
+		last := first
+		for j := first + 1; j < n; {
+			if lines[j].Type != LineContext {
+				last = j
+				j++
+				continue
+			}
+			k := j
+			for k < n && lines[k].Type == LineContext {
+				k++
+			}
+			if k == n || k-j > 2*context {
+				break
+			}
+			j = k
+		}
+
+		start := max(first-context, i)
+		end := min(last+context+1, n)
+		hunks = append(hunks, newHunk(lines[start:end]))
+		i = end
+	}
+	return hunks
+}
+
+func newHunk(lines []numberedLine) Hunk {
+	h := Hunk{
+		OldStart: lines[0].oldPos,
+		NewStart: lines[0].newPos,
+		Lines:    make([]DiffLine, 0, len(lines)),
+	}
+	for _, l := range lines {
+		if l.Type != LineAddition {
+			h.OldLines++
+		}
+		if l.Type != LineDeletion {
+			h.NewLines++
+		}
+		h.Lines = append(h.Lines, l.DiffLine)
+	}
+
+	// an empty side is addressed by the line before it, as in unified diffs
+	if h.OldLines == 0 {
+		h.OldStart--
+	}
+	if h.NewLines == 0 {
+		h.NewStart--
+	}
+	h.Header = fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	return h
+}
+
+// FormatPatch renders a commit and its patch as an email, like git format-patch
+func FormatPatch(c *object.Commit, p *object.Patch) string {
+	subject, body, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From %s Mon Sep 17 00:00:00 2001\n", c.Hash)
+	fmt.Fprintf(&b, "From: %s <%s>\n", c.Author.Name, c.Author.Email)
+	fmt.Fprintf(&b, "Date: %s\n", c.Author.When.Format("Mon, 2 Jan 2006 15:04:05 -0700"))
+	fmt.Fprintf(&b, "Subject: [PATCH] %s\n\n", subject)
+	if body = strings.TrimSpace(body); body != "" {
+		b.WriteString(body + "\n")
+	}
+	b.WriteString("---\n")
+	b.WriteString(p.Stats().String())
+	b.WriteString("\n")
+	b.WriteString(p.String())
+	b.WriteString("-- \nmini-github\n")
+	return b.String()
+}
@@ -0,0 +1,199 @@
+package gitutil
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// ErrRefNotFound is returned when a branch, tag or commit can't be resolved
+var ErrRefNotFound = errors.New("ref not found")
+
+// Signature is an author or committer line
+type Signature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	Date  time.Time `json:"date"`
+}
+
+// Trailer is a "Key: value" line at the end of a commit message, such as Signed-off-by
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// CommitInfo is the JSON representation of a commit
+type CommitInfo struct {
+	SHA       string    `json:"sha"`
+	Message   string    `json:"message"`
+	Author    Signature `json:"author"`
+	Committer Signature `json:"committer"`
+	Parents   []string  `json:"parents"`
+	Trailers  []Trailer `json:"trailers"`
+}
+
+// NewCommitInfo converts a go-git commit
+func NewCommitInfo(c *object.Commit) CommitInfo {
+	parents := make([]string, 0, len(c.ParentHashes))
+	for _, p := range c.ParentHashes {
+		parents = append(parents, p.String())
+	}
+	return CommitInfo{
+		SHA:       c.Hash.String(),
+		Message:   c.Message,
+		Author:    newSignature(c.Author),
+		Committer: newSignature(c.Committer),
+		Parents:   parents,
+		Trailers:  ParseTrailers(c.Message),
+	}
+}
+
+var trailerKey = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// ParseTrailers returns the trailers in the last paragraph of message. As in
+// git interpret-trailers, the paragraph only counts if every line is a trailer
+// or the continuation of one.
+func ParseTrailers(message string) []Trailer {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	paragraphs := strings.Split(message, "\n\n")
+	if len(paragraphs) < 2 {
+		return []Trailer{}
+	}
+
+	trailers := []Trailer{}
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		if len(trailers) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !trailerKey.MatchString(key) {
+			return []Trailer{}
+		}
+		trailers = append(trailers, Trailer{Key: key, Value: strings.TrimSpace(value)})
+	}
+	return trailers
+}
+
+func newSignature(s object.Signature) Signature {
+	return Signature{Name: s.Name, Email: s.Email, Date: s.When}
+}
+
+// ResolveCommit resolves a branch, tag, full or abbreviated SHA to a commit.
+// As in git, refs win over abbreviated SHAs, so a branch named "cafe" isn't
+// mistaken for a commit starting with cafe.
+func ResolveCommit(r *git.Repository, rev string) (*object.Commit, error) {
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.ReferenceName(rev),
+		plumbing.NewBranchReferenceName(rev),
+		plumbing.NewTagReferenceName(rev),
+	} {
+		if ref, err := r.Reference(name, true); err == nil {
+			return PeelToCommit(r, ref.Hash())
+		}
+	}
+
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, ErrRefNotFound
+	}
+	return PeelToCommit(r, *hash)
+}
+
+// PeelToCommit returns the commit hash points to, following annotated tags
+func PeelToCommit(r *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	for {
+		if c, err := r.CommitObject(hash); err == nil {
+			return c, nil
+		}
+		tag, err := r.TagObject(hash)
+		if err != nil {
+			return nil, ErrRefNotFound
+		}
+		hash = tag.Target
+	}
+}
+
+// ResolveRefAndPath splits "ref/some/path" where the ref itself may contain
+// slashes (feature/login). The shortest prefix that resolves wins.
+func ResolveRefAndPath(r *git.Repository, refAndPath string) (*object.Commit, string, string, error) {
+	parts := strings.Split(strings.Trim(refAndPath, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		ref := strings.Join(parts[:i], "/")
+		if c, err := ResolveCommit(r, ref); err == nil {
+			return c, ref, strings.Join(parts[i:], "/"), nil
+		}
+	}
+	return nil, "", "", ErrRefNotFound
+}
+
+// LastCommits finds, for each name in the directory dir of from's tree, the most
+// recent commit that changed it. History is followed along first parents.
+func LastCommits(r *git.Repository, from *object.Commit, dir string, names []string) (map[string]*object.Commit, error) {
+	result := make(map[string]*object.Commit, len(names))
+	pending := make(map[string]bool, len(names))
+	for _, n := range names {
+		pending[n] = true
+	}
+
+	current := from
+	currentEntries, err := dirEntries(current, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for len(pending) > 0 {
+		var parent *object.Commit
+		parentEntries := map[string]plumbing.Hash{}
+		if current.NumParents() > 0 {
+			if parent, err = current.Parent(0); err != nil {
+				return nil, err
+			}
+			if parentEntries, err = dirEntries(parent, dir); err != nil {
+				return nil, err
+			}
+		}
+
+		for name := range pending {
+			if h, ok := parentEntries[name]; !ok || h != currentEntries[name] {
+				result[name] = current
+				delete(pending, name)
+			}
+		}
+
+		if parent == nil {
+			break
+		}
+		current, currentEntries = parent, parentEntries
+	}
+
+	return result, nil
+}
+
+// dirEntries maps entry names to hashes for dir in c's tree. A missing
+// directory yields no entries.
+func dirEntries(c *object.Commit, dir string) (map[string]plumbing.Hash, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if tree, err = tree.Tree(dir); err != nil {
+			if errors.Is(err, object.ErrDirectoryNotFound) {
+				return map[string]plumbing.Hash{}, nil
+			}
+			return nil, err
+		}
+	}
+
+	entries := make(map[string]plumbing.Hash, len(tree.Entries))
+	for _, e := range tree.Entries {
+		entries[e.Name] = e.Hash
+	}
+	return entries, nil
+}
@@ -0,0 +1,66 @@
+package gitutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+var errBadCursor = errors.New("invalid cursor")
+
+// LogCursor is where a page of history left off: the commit the walk started
+// from and how many commits of it came before the next page. Resuming the
+// same walk keeps commits reached through any parent of a merge.
+type LogCursor struct {
+	From plumbing.Hash
+	Skip int
+}
+
+func (c LogCursor) String() string {
+	return fmt.Sprintf("%s.%d", c.From, c.Skip)
+}
+
+// ParseLogCursor reads a cursor written by LogCursor.String
+func ParseLogCursor(s string) (LogCursor, error) {
+	hash, skip, ok := strings.Cut(s, ".")
+	n, err := strconv.Atoi(skip)
+	if !ok || err != nil || n < 0 || !plumbing.IsHash(hash) {
+		return LogCursor{}, errBadCursor
+	}
+	return LogCursor{From: plumbing.NewHash(hash), Skip: n}, nil
+}
+
+// LogPage returns up to n commits of the log opts describes that keep
+// accepts, leaving out the first skip commits of the walk. next is the skip
+// for the following page, zero once the log is exhausted.
+func LogPage(r *git.Repository, opts *git.LogOptions, skip, n int, keep func(*object.Commit) bool) (commits []*object.Commit, next int, err error) {
+	iter, err := r.Log(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer iter.Close()
+
+	i := -1
+	err = iter.ForEach(func(c *object.Commit) error {
+		i++
+		if i < skip || !keep(c) {
+			return nil
+		}
+		if len(commits) == n {
+			next = i
+			return storer.ErrStop
+		}
+		commits = append(commits, c)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return commits, next, nil
+}
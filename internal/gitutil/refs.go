@@ -0,0 +1,33 @@
+package gitutil
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ValidBranchName reports whether name is allowed as a branch, following git check-ref-format
+func ValidBranchName(name string) bool {
+	return name != "HEAD" && plumbing.NewBranchReferenceName(name).Validate() == nil
+}
+
+// ValidTagName reports whether name is allowed as a tag
+func ValidTagName(name string) bool {
+	return plumbing.NewTagReferenceName(name).Validate() == nil
+}
+
+// SetHead points HEAD at branch, which doesn't have to exist yet
+func SetHead(r *git.Repository, branch string) error {
+	return r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(branch)))
+}
+
+// HasBranches reports whether anything has been pushed to the repository yet
+func HasBranches(r *git.Repository) (bool, error) {
+	iter, err := r.Branches()
+	if err != nil {
+		return false, err
+	}
+	defer iter.Close()
+
+	_, err = iter.Next()
+	return err == nil, nil
+}
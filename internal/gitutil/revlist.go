@@ -0,0 +1,114 @@
+package gitutil
+
+import (
+	"container/heap"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	reachInclude uint8 = 1 << iota
+	reachExclude
+)
+
+// RevList returns the commits reachable from include but not from any of
+// exclude, newest first, like git rev-list include ^exclude...
+func RevList(include *object.Commit, exclude ...*object.Commit) ([]*object.Commit, error) {
+	flags := map[plumbing.Hash]uint8{}
+	queue := &commitQueue{}
+	// queued holds the commits waiting in queue, and interesting how many of
+	// them aren't reachable from exclude, so the walk can stop without
+	// scanning the queue once nothing left can be an included commit
+	queued := map[plumbing.Hash]bool{}
+	interesting := 0
+
+	push := func(c *object.Commit, f uint8) {
+		old := flags[c.Hash]
+		if old|f == old {
+			return
+		}
+		flags[c.Hash] = old | f
+		if queued[c.Hash] {
+			// still waiting, its new flags are read when it's popped
+			if f&reachExclude != 0 && old&reachExclude == 0 {
+				interesting--
+			}
+			return
+		}
+		queued[c.Hash] = true
+		heap.Push(queue, c)
+		if flags[c.Hash]&reachExclude == 0 {
+			interesting++
+		}
+	}
+	push(include, reachInclude)
+	for _, c := range exclude {
+		push(c, reachExclude)
+	}
+
+	var result []*object.Commit
+	for interesting > 0 {
+		c := heap.Pop(queue).(*object.Commit)
+		delete(queued, c.Hash)
+		f := flags[c.Hash]
+
+		// a commit is only walked again once it's reached from exclude, so
+		// it's collected at most once
+		if f == reachInclude {
+			interesting--
+			result = append(result, c)
+		}
+
+		err := c.Parents().ForEach(func(p *object.Commit) error {
+			push(p, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// a commit can be reached from exclude after it was collected when
+	// committer dates are skewed; drop those
+	filtered := result[:0]
+	for _, c := range result {
+		if flags[c.Hash] == reachInclude {
+			filtered = append(filtered, c)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Committer.When.After(filtered[j].Committer.When)
+	})
+	return filtered, nil
+}
+
+// commitQueue pops the most recently committed commit first
+type commitQueue []*object.Commit
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	return q[i].Committer.When.After(q[j].Committer.When)
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*object.Commit)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// AheadBehind counts commits on head that aren't on base and vice versa
+func AheadBehind(base, head *object.Commit) (ahead, behind int, err error) {
+	a, err := RevList(head, base)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := RevList(base, head)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(a), len(b), nil
+}
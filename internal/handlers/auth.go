@@ -28,6 +28,7 @@ func RegisterHandler(database *db.DB, mailer *mail.Mailer) gin.HandlerFunc {
 			Username string `json:"username" binding:"required,min=3"`
 			Email    string `json:"email" binding:"required,email"`
 			Password string `json:"password" binding:"required,min=6"`
+			Locale   string `json:"locale" binding:"omitempty,max=16"`
 		}
 
 		if err := c.ShouldBindJSON(&payload); err != nil {
@@ -48,12 +49,12 @@ func RegisterHandler(database *db.DB, mailer *mail.Mailer) gin.HandlerFunc {
 		}
 
 		var existing2 db.User
-		if err := database.Where("username = ?", payload.Username).First(&existing2).Error; err == nil {
+		if err := database.Where("lower(username) = lower(?)", payload.Username).First(&existing2).Error; err == nil {
 			responses.JSONError(c, http.StatusBadRequest, "username already in use")
 			return
 		}
 
-		user := db.User{Username: payload.Username, Email: payload.Email, Password: string(hash)}
+		user := db.User{Username: payload.Username, Email: payload.Email, Password: string(hash), Locale: payload.Locale}
 		if err := database.Create(&user).Error; err != nil {
 			responses.JSONError(c, http.StatusBadRequest, "error creating user")
 			return
@@ -126,7 +127,7 @@ func ResendVerificationHandler(database *db.DB, mailer *mail.Mailer) gin.Handler
 	}
 }
 
-func LoginHandler(database *db.DB, accessSecret, refreshSecret string, refreshTTL time.Duration) gin.HandlerFunc {
+func LoginHandler(database *db.DB, accessSecret, refreshSecret string, accessTTL, refreshTTL time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var payload struct{ Email, Password string }
 		if err := c.ShouldBindJSON(&payload); err != nil {
@@ -150,11 +151,16 @@ func LoginHandler(database *db.DB, accessSecret, refreshSecret string, refreshTT
 			return
 		}
 
+		if user.Disabled() {
+			responses.JSONError(c, http.StatusForbidden, "account disabled")
+			return
+		}
+
 		// create access token with jti
 		jtiBytes := make([]byte, 16)
 		rand.Read(jtiBytes)
 		jti := hex.EncodeToString(jtiBytes)
-		access := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": user.ID, "exp": time.Now().Add(15 * time.Minute).Unix(), "jti": jti})
+		access := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": user.ID, "exp": time.Now().Add(accessTTL).Unix(), "jti": jti})
 		accessString, _ := access.SignedString([]byte(accessSecret))
 
 		// create refresh token (random) and store in redis
@@ -163,7 +169,7 @@ func LoginHandler(database *db.DB, accessSecret, refreshSecret string, refreshTT
 		refreshToken := hex.EncodeToString(refreshBytes)
 		redis.SetRefreshToken(refreshToken, user.ID, refreshTTL)
 
-		responses.JSONSuccess(c, http.StatusOK, "login successful", gin.H{"access_token": accessString, "refresh_token": refreshToken, "token_type": "bearer", "expires_in": 900})
+		responses.JSONSuccess(c, http.StatusOK, "login successful", gin.H{"access_token": accessString, "refresh_token": refreshToken, "token_type": "bearer", "expires_in": int(accessTTL.Seconds())})
 	}
 }
 
@@ -188,6 +194,13 @@ func RefreshHandler(database *db.DB, accessSecret, refreshSecret string, accessT
 		var id uint
 		fmt.Sscanf(val, "%d", &id)
 
+		// sessions of disabled users end when their access token expires
+		if disabled, err := database.UserDisabled(id); err != nil || disabled {
+			redis.RevokeRefreshToken(payload.Refresh)
+			responses.JSONError(c, 401, "invalid refresh token")
+			return
+		}
+
 		// issue new access token
 		jtiBytes := make([]byte, 16)
 		rand.Read(jtiBytes)
@@ -255,16 +268,14 @@ func RequestPasswordResetHandler(database *db.DB, mailer *mail.Mailer) gin.Handl
 		rand.Read(tok)
 		code := hex.EncodeToString(tok)
 		redis.Client.Set(redis.Ctx, "pwreset:"+code, user.ID, time.Hour*1)
-		link := fmt.Sprintf("http://localhost:8080/api/v1/auth/reset?token=%s", code)
 
 		if mailer != nil {
-			go func() {
-				if err := mailer.Send(user.Email, "Password reset", fmt.Sprintf("Click <a href=\"%s\">here</a>", link)); err != nil {
-					log.Logger.Error("failed to send password reset email", zap.Error(err))
-				} else {
-					log.Logger.Info("password reset email sent", zap.String("to", user.Email))
-				}
-			}()
+			data := map[string]any{"Username": user.Username, "Token": code}
+			if err := mailer.EnqueueTemplate(c.Request.Context(), user.Email, user.Locale, "password_reset", data); err != nil {
+				log.Logger.Error("failed to queue password reset email", zap.Error(err))
+			} else {
+				log.Logger.Info("password reset email queued", zap.String("to", user.Email))
+			}
 		}
 
 		responses.JSONSuccess(c, 200, "please chack your email", gin.H{"ok": true})
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRepoBlob returns a file at a branch, tag or commit.
+// ?format=raw streams the content (with Range support), the default is JSON
+// with the content base64 encoded.
+func GetRepoBlob(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "raw" {
+			responses.JSONError(c, http.StatusBadRequest, "format must be json or raw")
+			return
+		}
+
+		_, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		commit, ref, filePath, err := gitutil.ResolveRefAndPath(r, c.Param("ref")+c.Param("path"))
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "ref not found")
+			return
+		}
+
+		file, err := commit.File(filePath)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "file not found")
+			return
+		}
+
+		reader, err := file.Reader()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot read file")
+			return
+		}
+		defer reader.Close()
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot read file")
+			return
+		}
+		binary := isBinary(content)
+
+		if format == "raw" {
+			c.Header("Content-Type", rawContentType(file.Name, content, binary))
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Header("ETag", `"`+file.Hash.String()+`"`)
+			http.ServeContent(c.Writer, c.Request, path.Base(file.Name), commit.Committer.When, bytes.NewReader(content))
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+			"name":     path.Base(file.Name),
+			"path":     file.Name,
+			"sha":      file.Hash.String(),
+			"size":     file.Size,
+			"binary":   binary,
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString(content),
+			"ref":      ref,
+			"commit":   commit.Hash.String(),
+		})
+	}
+}
+
+// isBinary uses Git's heuristic: a NUL byte in the first 8000 bytes
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) != -1
+}
+
+// rawContentType picks the Content-Type for raw downloads. Text is always
+// served as plain text so repository content can't run as HTML on our origin.
+func rawContentType(name string, content []byte, binary bool) string {
+	if !binary {
+		return "text/plain; charset=utf-8"
+	}
+	if t := mime.TypeByExtension(path.Ext(name)); t != "" && !strings.HasPrefix(t, "text/") {
+		return t
+	}
+	if t := http.DetectContentType(content); !strings.HasPrefix(t, "text/") {
+		return t
+	}
+	return "application/octet-stream"
+}
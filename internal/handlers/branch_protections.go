@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+)
+
+type branchProtectionPayload struct {
+	Pattern              string   `json:"pattern" binding:"required"`
+	BlockForcePushes     bool     `json:"block_force_pushes"`
+	BlockDeletions       bool     `json:"block_deletions"`
+	RequireLinearHistory bool     `json:"require_linear_history"`
+	RestrictPushes       bool     `json:"restrict_pushes"`
+	AllowedPushers       []string `json:"allowed_pushers"` // usernames
+}
+
+type branchProtectionInfo struct {
+	db.BranchProtection
+	AllowedPushers []string `json:"allowed_pushers"`
+}
+
+// ListBranchProtections lists the branch protection rules of a repository
+func ListBranchProtections(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		var rules []db.BranchProtection
+		if err := dbConn.Where("repository_id = ?", repo.ID).Order("id").Find(&rules).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fetch branch protections")
+			return
+		}
+
+		infos := []branchProtectionInfo{}
+		for _, rule := range rules {
+			info, err := newBranchProtectionInfo(dbConn, rule)
+			if err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "failed to fetch branch protections")
+				return
+			}
+			infos = append(infos, info)
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", infos)
+	}
+}
+
+// CreateBranchProtection protects the branches matching a pattern
+func CreateBranchProtection(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload branchProtectionPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		rule := db.BranchProtection{RepositoryID: repo.ID}
+		if !applyBranchProtectionPayload(c, dbConn, &rule, &payload) {
+			return
+		}
+
+		var count int64
+		dbConn.Model(&db.BranchProtection{}).Where("repository_id = ? AND pattern = ?", repo.ID, rule.Pattern).Count(&count)
+		if count > 0 {
+			responses.JSONError(c, http.StatusConflict, "a rule for this pattern already exists")
+			return
+		}
+
+		if err := dbConn.Create(&rule).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save branch protection")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusCreated, "branch protection created", branchProtectionInfo{rule, payload.AllowedPushers})
+	}
+}
+
+// UpdateBranchProtection replaces a branch protection rule
+func UpdateBranchProtection(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload branchProtectionPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		var rule db.BranchProtection
+		if err := dbConn.Where("id = ? AND repository_id = ?", c.Param("protection_id"), repo.ID).First(&rule).Error; err != nil {
+			responses.JSONError(c, http.StatusNotFound, "branch protection not found")
+			return
+		}
+		if !applyBranchProtectionPayload(c, dbConn, &rule, &payload) {
+			return
+		}
+
+		var count int64
+		dbConn.Model(&db.BranchProtection{}).Where("repository_id = ? AND pattern = ? AND id <> ?", repo.ID, rule.Pattern, rule.ID).Count(&count)
+		if count > 0 {
+			responses.JSONError(c, http.StatusConflict, "a rule for this pattern already exists")
+			return
+		}
+
+		if err := dbConn.Save(&rule).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save branch protection")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "branch protection updated", branchProtectionInfo{rule, payload.AllowedPushers})
+	}
+}
+
+// DeleteBranchProtection removes a branch protection rule
+func DeleteBranchProtection(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		res := dbConn.Where("id = ? AND repository_id = ?", c.Param("protection_id"), repo.ID).Delete(&db.BranchProtection{})
+		if res.Error != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to delete branch protection")
+			return
+		}
+		if res.RowsAffected == 0 {
+			responses.JSONError(c, http.StatusNotFound, "branch protection not found")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "branch protection deleted", nil)
+	}
+}
+
+// applyBranchProtectionPayload validates payload and copies it onto rule,
+// resolving the allowed pushers' usernames. On failure the response has been written.
+func applyBranchProtectionPayload(c *gin.Context, dbConn *db.DB, rule *db.BranchProtection, payload *branchProtectionPayload) bool {
+	if _, err := path.Match(payload.Pattern, ""); err != nil {
+		responses.JSONError(c, http.StatusBadRequest, "invalid branch pattern")
+		return false
+	}
+
+	if payload.AllowedPushers == nil {
+		payload.AllowedPushers = []string{}
+	}
+	ids := []uint{}
+	for _, username := range payload.AllowedPushers {
+		var user db.User
+		if err := dbConn.Where("username = ?", username).First(&user).Error; err != nil {
+			responses.JSONError(c, http.StatusUnprocessableEntity, "unknown user "+username)
+			return false
+		}
+		ids = append(ids, user.ID)
+	}
+
+	rule.Pattern = payload.Pattern
+	rule.BlockForcePushes = payload.BlockForcePushes
+	rule.BlockDeletions = payload.BlockDeletions
+	rule.RequireLinearHistory = payload.RequireLinearHistory
+	rule.RestrictPushes = payload.RestrictPushes
+	rule.AllowedPusherIDs = ids
+	return true
+}
+
+func newBranchProtectionInfo(dbConn *db.DB, rule db.BranchProtection) (branchProtectionInfo, error) {
+	names := []string{}
+	if len(rule.AllowedPusherIDs) > 0 {
+		if err := dbConn.Model(&db.User{}).Where("id IN ?", rule.AllowedPusherIDs).Order("username").Pluck("username", &names).Error; err != nil {
+			return branchProtectionInfo{}, err
+		}
+	}
+	return branchProtectionInfo{rule, names}, nil
+}
+
+// deletionBlocked reports whether a branch protection rule forbids deleting branch
+func deletionBlocked(dbConn *db.DB, repo *db.Repository, branch string) (bool, error) {
+	rules, err := dbConn.BranchProtections(repo.ID, branch)
+	if err != nil {
+		return false, err
+	}
+	for _, r := range rules {
+		if r.BlockDeletions {
+			return true, nil
+		}
+	}
+	return false, nil
+}
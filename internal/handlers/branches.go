@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.uber.org/zap"
+)
+
+type branchInfo struct {
+	Name      string             `json:"name"`
+	Commit    gitutil.CommitInfo `json:"commit"`
+	IsDefault bool               `json:"default"`
+	Ahead     int                `json:"ahead"`  // commits not on the default branch
+	Behind    int                `json:"behind"` // default branch commits missing here
+}
+
+// ListBranches lists branches with their head commit and how far they are from the default branch
+func ListBranches(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		defaultCommit, _ := branchCommit(r, repo.DefaultBranch)
+
+		iter, err := r.Branches()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot list branches")
+			return
+		}
+		defer iter.Close()
+
+		branches := []branchInfo{}
+		err = iter.ForEach(func(ref *plumbing.Reference) error {
+			cmt, err := r.CommitObject(ref.Hash())
+			if err != nil {
+				return err
+			}
+			b := branchInfo{
+				Name:      ref.Name().Short(),
+				Commit:    gitutil.NewCommitInfo(cmt),
+				IsDefault: ref.Name().Short() == repo.DefaultBranch,
+			}
+			if defaultCommit != nil && !b.IsDefault {
+				if b.Ahead, b.Behind, err = gitutil.AheadBehind(defaultCommit, cmt); err != nil {
+					return err
+				}
+			}
+			branches = append(branches, b)
+			return nil
+		})
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot list branches")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", branches)
+	}
+}
+
+// CreateBranch creates a branch from a ref, the default branch if none is given
+func CreateBranch(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name string `json:"name" binding:"required"`
+			From string `json:"from"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+		if !gitutil.ValidBranchName(payload.Name) {
+			responses.JSONError(c, http.StatusBadRequest, "invalid branch name")
+			return
+		}
+
+		repo, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		if payload.From == "" {
+			payload.From = repo.DefaultBranch
+		}
+		from, err := gitutil.ResolveCommit(r, payload.From)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "ref not found")
+			return
+		}
+
+		name := plumbing.NewBranchReferenceName(payload.Name)
+		if _, err := r.Reference(name, false); err == nil {
+			responses.JSONError(c, http.StatusConflict, "branch already exists")
+			return
+		}
+		if err := r.Storer.SetReference(plumbing.NewHashReference(name, from.Hash)); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to create branch")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusCreated, "branch created", branchInfo{
+			Name:      payload.Name,
+			Commit:    gitutil.NewCommitInfo(from),
+			IsDefault: payload.Name == repo.DefaultBranch,
+		})
+	}
+}
+
+// RenameBranch renames a branch. Renaming the default branch moves the default with it.
+func RenameBranch(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+		if !gitutil.ValidBranchName(payload.Name) {
+			responses.JSONError(c, http.StatusBadRequest, "invalid branch name")
+			return
+		}
+
+		repo, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		oldName := branchParam(c)
+		old, err := r.Reference(plumbing.NewBranchReferenceName(oldName), false)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "branch not found")
+			return
+		}
+		if !checkDeletionAllowed(c, dbConn, repo, oldName) {
+			return
+		}
+		newRef := plumbing.NewBranchReferenceName(payload.Name)
+		if _, err := r.Reference(newRef, false); err == nil {
+			responses.JSONError(c, http.StatusConflict, "branch already exists")
+			return
+		}
+
+		if err := r.Storer.SetReference(plumbing.NewHashReference(newRef, old.Hash())); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to rename branch")
+			return
+		}
+		if err := r.Storer.RemoveReference(old.Name()); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to rename branch")
+			return
+		}
+
+		if oldName == repo.DefaultBranch {
+			if err := setDefaultBranch(dbConn, repo, r, payload.Name); err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "failed to update default branch")
+				return
+			}
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "branch renamed", gin.H{
+			"name":     payload.Name,
+			"old_name": oldName,
+		})
+	}
+}
+
+// DeleteBranch deletes a branch other than the default branch
+func DeleteBranch(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		name := branchParam(c)
+		if name == repo.DefaultBranch {
+			responses.JSONError(c, http.StatusUnprocessableEntity, "cannot delete the default branch")
+			return
+		}
+
+		ref := plumbing.NewBranchReferenceName(name)
+		existing, err := r.Reference(ref, false)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "branch not found")
+			return
+		}
+		if !checkDeletionAllowed(c, dbConn, repo, name) {
+			return
+		}
+		if err := r.Storer.RemoveReference(ref); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to delete branch")
+			return
+		}
+
+		if err := webhooks.EmitBranchDeleted(dbConn, repo, c.MustGet("user_id").(uint), name, existing.Hash().String()); err != nil {
+			log.Logger.Error("failed to queue branch_deleted webhooks", zap.Error(err))
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "branch deleted", nil)
+	}
+}
+
+// SetDefaultBranch changes which branch HEAD follows
+func SetDefaultBranch(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name string `json:"name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+		if !gitutil.ValidBranchName(payload.Name) {
+			responses.JSONError(c, http.StatusBadRequest, "invalid branch name")
+			return
+		}
+
+		repo, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		// an empty repository may name the branch its first push will create
+		hasBranches, err := gitutil.HasBranches(r)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot list branches")
+			return
+		}
+		if _, err := r.Reference(plumbing.NewBranchReferenceName(payload.Name), false); err != nil && hasBranches {
+			responses.JSONError(c, http.StatusNotFound, "branch not found")
+			return
+		}
+
+		if err := setDefaultBranch(dbConn, repo, r, payload.Name); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to update default branch")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "default branch updated", gin.H{"default_branch": payload.Name})
+	}
+}
+
+// setDefaultBranch records the default branch and points HEAD at it
+func setDefaultBranch(dbConn *db.DB, repo *db.Repository, r *git.Repository, branch string) error {
+	if err := gitutil.SetHead(r, branch); err != nil {
+		return err
+	}
+	repo.DefaultBranch = branch
+	return dbConn.Model(repo).Update("default_branch", branch).Error
+}
+
+// checkDeletionAllowed rejects removing a branch protected against deletion,
+// which renaming also does. On failure the response has been written.
+func checkDeletionAllowed(c *gin.Context, dbConn *db.DB, repo *db.Repository, branch string) bool {
+	blocked, err := deletionBlocked(dbConn, repo, branch)
+	if err != nil {
+		responses.JSONError(c, http.StatusInternalServerError, "failed to check branch protection")
+		return false
+	}
+	if blocked {
+		responses.JSONError(c, http.StatusUnprocessableEntity, "protected branch "+branch+" cannot be deleted")
+		return false
+	}
+	return true
+}
+
+// branchParam reads the *branch wildcard, which keeps slashes in names like feature/login
+func branchParam(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("branch"), "/")
+}
+
+func branchCommit(r *git.Repository, branch string) (*object.Commit, error) {
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), false)
+	if err != nil {
+		return nil, err
+	}
+	return r.CommitObject(ref.Hash())
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	defaultPerPage = 30
+	maxPerPage     = 100
+)
+
+var errPerPage = fmt.Errorf("per_page must be between 1 and %d", maxPerPage)
+
+// ListRepoCommits lists commits reachable from a ref, newest first.
+// Filters: ref, path, author (name or email substring), since and until (RFC 3339).
+// Pass the returned next_cursor as ?cursor= along with the same filters to
+// fetch the following page.
+func ListRepoCommits(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		perPage, err := perPageParam(c)
+		if err != nil {
+			responses.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		opts := git.LogOptions{Order: git.LogOrderCommitterTime}
+		for param, dst := range map[string]**time.Time{"since": &opts.Since, "until": &opts.Until} {
+			if v := c.Query(param); v != "" {
+				t, err := time.Parse(time.RFC3339, v)
+				if err != nil {
+					responses.JSONError(c, http.StatusBadRequest, param+" must be an RFC 3339 timestamp")
+					return
+				}
+				*dst = &t
+			}
+		}
+
+		if p := strings.Trim(c.Query("path"), "/"); p != "" {
+			opts.PathFilter = func(changed string) bool {
+				return changed == p || strings.HasPrefix(changed, p+"/")
+			}
+		}
+
+		_, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		// the cursor resumes the walk it came from, so it replaces the ref
+		var cursor gitutil.LogCursor
+		if v := c.Query("cursor"); v != "" {
+			if cursor, err = gitutil.ParseLogCursor(v); err != nil {
+				responses.JSONError(c, http.StatusBadRequest, err.Error())
+				return
+			}
+		} else {
+			from, err := gitutil.ResolveCommit(r, c.DefaultQuery("ref", "HEAD"))
+			if err != nil {
+				responses.JSONError(c, http.StatusNotFound, "ref not found")
+				return
+			}
+			cursor.From = from.Hash
+		}
+		opts.From = cursor.From
+
+		author := strings.ToLower(c.Query("author"))
+		page, next, err := gitutil.LogPage(r, &opts, cursor.Skip, perPage, func(cmt *object.Commit) bool {
+			return author == "" ||
+				strings.Contains(strings.ToLower(cmt.Author.Name), author) ||
+				strings.Contains(strings.ToLower(cmt.Author.Email), author)
+		})
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot get commit logs")
+			return
+		}
+
+		commits := make([]gitutil.CommitInfo, len(page))
+		for i, cmt := range page {
+			commits[i] = gitutil.NewCommitInfo(cmt)
+		}
+		var nextCursor string
+		if next > 0 {
+			nextCursor = gitutil.LogCursor{From: cursor.From, Skip: next}.String()
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+			"commits":     commits,
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+func perPageParam(c *gin.Context) (int, error) {
+	v := c.Query("per_page")
+	if v == "" {
+		return defaultPerPage, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxPerPage {
+		return 0, errPerPage
+	}
+	return n, nil
+}
+
+// GetRepoCommit returns a commit with its diff against the first parent.
+// Append .diff or .patch to the SHA (or pass ?format=diff|patch) for raw output.
+func GetRepoCommit(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sha, format := c.Param("sha"), c.DefaultQuery("format", "json")
+		for _, ext := range []string{"diff", "patch"} {
+			if s, ok := strings.CutSuffix(sha, "."+ext); ok {
+				sha, format = s, ext
+			}
+		}
+		if format != "json" && format != "diff" && format != "patch" {
+			responses.JSONError(c, http.StatusBadRequest, "format must be json, diff or patch")
+			return
+		}
+
+		_, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		cmt, err := gitutil.ResolveCommit(r, sha)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "commit not found")
+			return
+		}
+
+		patch, err := gitutil.CommitPatch(c.Request.Context(), cmt)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot diff commit")
+			return
+		}
+
+		switch format {
+		case "diff":
+			c.String(http.StatusOK, patch.String())
+		case "patch":
+			c.String(http.StatusOK, gitutil.FormatPatch(cmt, patch))
+		default:
+			responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+				"commit": gitutil.NewCommitInfo(cmt),
+				"diff":   gitutil.NewDiff(patch),
+			})
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareCommits caps how many commits a comparison lists; counts are always exact
+const maxCompareCommits = 250
+
+// CompareRefs compares two refs given as base...head. Like a pull request,
+// the diff shows what head changed since it branched off base.
+func CompareRefs(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		baseRef, headRef, ok := strings.Cut(strings.TrimPrefix(c.Param("basehead"), "/"), "...")
+		if !ok || baseRef == "" || headRef == "" {
+			responses.JSONError(c, http.StatusBadRequest, "expected base...head")
+			return
+		}
+
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "diff" {
+			responses.JSONError(c, http.StatusBadRequest, "format must be json or diff")
+			return
+		}
+
+		_, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		base, err := gitutil.ResolveCommit(r, baseRef)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "base ref not found")
+			return
+		}
+		head, err := gitutil.ResolveCommit(r, headRef)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "head ref not found")
+			return
+		}
+
+		bases, err := base.MergeBase(head)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot find merge base")
+			return
+		}
+		if len(bases) == 0 {
+			responses.JSONError(c, http.StatusUnprocessableEntity, "base and head have no common history")
+			return
+		}
+		mergeBase := bases[0]
+
+		ahead, err := gitutil.RevList(head, base)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot walk history")
+			return
+		}
+		behind, err := gitutil.RevList(base, head)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot walk history")
+			return
+		}
+
+		mergeBaseTree, err := mergeBase.Tree()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot read tree")
+			return
+		}
+		headTree, err := head.Tree()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot read tree")
+			return
+		}
+		patch, err := gitutil.TreePatch(c.Request.Context(), mergeBaseTree, headTree)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot diff refs")
+			return
+		}
+
+		if format == "diff" {
+			c.String(http.StatusOK, patch.String())
+			return
+		}
+
+		// list ahead commits oldest first, the order they'd be applied in
+		commits := []gitutil.CommitInfo{}
+		for i := len(ahead) - 1; i >= 0 && len(commits) < maxCompareCommits; i-- {
+			commits = append(commits, gitutil.NewCommitInfo(ahead[i]))
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+			"base":       gitutil.NewCommitInfo(base),
+			"head":       gitutil.NewCommitInfo(head),
+			"merge_base": gitutil.NewCommitInfo(mergeBase),
+			"status":     compareStatus(len(ahead), len(behind)),
+			"ahead_by":   len(ahead),
+			"behind_by":  len(behind),
+			"commits":    commits,
+			"diff":       gitutil.NewDiff(patch),
+		})
+	}
+}
+
+func compareStatus(ahead, behind int) string {
+	switch {
+	case ahead == 0 && behind == 0:
+		return "identical"
+	case behind == 0:
+		return "ahead"
+	case ahead == 0:
+		return "behind"
+	default:
+		return "diverged"
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type pushEventInfo struct {
+	db.PushEvent
+	Pusher string `json:"pusher"`
+	Repo   string `json:"repo"` // owner/name
+}
+
+// ListRepoEvents lists the pushes to a repository, newest first
+func ListRepoEvents(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		listPushEvents(c, dbConn.Where("repository_id = ?", repo.ID))
+	}
+}
+
+// ListUserEvents lists a user's pushes to the repositories the caller can read, newest first
+func ListUserEvents(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user db.User
+		if err := dbConn.Where("username = ?", c.Param("username")).First(&user).Error; err != nil {
+			responses.JSONError(c, http.StatusNotFound, "user not found")
+			return
+		}
+
+		userID := c.MustGet("user_id").(uint)
+		visible := dbConn.Model(&db.Repository{}).Select("id").Where("visibility = ? OR owner_id = ?", "public", userID)
+		listPushEvents(c, dbConn.Where("pusher_id = ? AND repository_id IN (?)", user.ID, visible))
+	}
+}
+
+// listPushEvents writes a page of the events query selects. Pass the returned
+// next_cursor as ?cursor= to fetch the following page.
+func listPushEvents(c *gin.Context, query *gorm.DB) {
+	perPage, err := perPageParam(c)
+	if err != nil {
+		responses.JSONError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		query = query.Where("id <= ?", cursor)
+	}
+
+	var events []db.PushEvent
+	if err := query.Preload("Pusher").Preload("Repository.Owner").Order("id DESC").Limit(perPage + 1).Find(&events).Error; err != nil {
+		responses.JSONError(c, http.StatusInternalServerError, "failed to fetch events")
+		return
+	}
+
+	// the extra event starts the next page
+	var nextCursor string
+	if len(events) > perPage {
+		nextCursor = strconv.FormatUint(uint64(events[perPage].ID), 10)
+		events = events[:perPage]
+	}
+
+	infos := make([]pushEventInfo, 0, len(events))
+	for _, e := range events {
+		infos = append(infos, pushEventInfo{
+			PushEvent: e,
+			Pusher:    e.Pusher.Username,
+			Repo:      e.Repository.Owner.Username + "/" + e.Repository.Name,
+		})
+	}
+
+	responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+		"events":      infos,
+		"next_cursor": nextCursor,
+	})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/GordenArcher/mini-github/internal/webhooks"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CreateFork forks a repository into the authenticated user's namespace,
+// under the same name unless another is given. The fork starts with the
+// parent's branches and tags and borrows its objects instead of copying them.
+func CreateFork(dbConn *db.DB, publicURL, sshPort string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name string `json:"name"`
+		}
+		// the body is optional
+		if err := c.ShouldBindJSON(&payload); err != nil && !errors.Is(err, io.EOF) {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		parent, _, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+		userID := c.MustGet("user_id").(uint)
+		if parent.OwnerID == userID {
+			responses.JSONError(c, http.StatusUnprocessableEntity, "you can't fork your own repository")
+			return
+		}
+
+		name := payload.Name
+		if name == "" {
+			name = parent.Name
+		}
+		if err := db.ValidateRepositoryName(name); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var owner db.User
+		if err := dbConn.First(&owner, userID).Error; err != nil {
+			responses.JSONError(c, http.StatusUnauthorized, "user not found")
+			return
+		}
+		if taken, err := dbConn.RepositoryNameTaken(userID, name); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to check repository name")
+			return
+		} else if taken {
+			responses.JSONError(c, http.StatusConflict, "repository already exists")
+			return
+		}
+
+		path := storage.Location(userID, name)
+		if _, err := storage.Fork(c.Request.Context(), parent.Path, path); err != nil {
+			if errors.Is(err, storage.ErrExists) {
+				responses.JSONError(c, http.StatusConflict, "repository already exists")
+				return
+			}
+			log.Logger.Error("failed to fork repository", zap.Uint("repo", parent.ID), zap.Error(err))
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fork repository")
+			return
+		}
+
+		fork := db.Repository{
+			Name:          name,
+			Description:   parent.Description,
+			OwnerID:       userID,
+			ParentID:      &parent.ID,
+			Visibility:    parent.Visibility,
+			Path:          path,
+			DefaultBranch: parent.DefaultBranch,
+		}
+		if err := dbConn.CreateRepository(&fork); err != nil {
+			storage.Delete(path)
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save repo")
+			return
+		}
+
+		fork.Owner = owner
+		if err := webhooks.Emit(dbConn, &fork, userID, webhooks.EventRepositoryCreated, nil); err != nil {
+			log.Logger.Error("failed to queue repository_created webhooks", zap.Error(err))
+		}
+
+		responses.JSONSuccess(c, http.StatusCreated, "repository forked", gin.H{
+			"repo_name": name,
+			"parent":    parent.Owner.Username + "/" + parent.Name,
+			"clone_url": cloneURL(publicURL, owner.Username, name),
+			"ssh_url":   sshURL(publicURL, sshPort, owner.Username, name),
+		})
+	}
+}
+
+// ListForks lists the forks of a repository the caller can see, oldest first
+func ListForks(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		var userID uint
+		if v, ok := c.Get("user_id"); ok {
+			userID = v.(uint)
+		}
+
+		var all []db.Repository
+		if err := dbConn.Preload("Owner").Where("parent_id = ?", repo.ID).Order("id").Find(&all).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot fetch forks")
+			return
+		}
+		// owners are shown by username only, their emails stay private
+		visible := []forkInfo{}
+		for _, fork := range all {
+			if fork.CanRead(userID) {
+				visible = append(visible, forkInfo{
+					ID:          fork.ID,
+					Owner:       fork.Owner.Username,
+					Name:        fork.Name,
+					Description: fork.Description,
+					Visibility:  fork.Visibility,
+					CreatedAt:   fork.CreatedAt,
+				})
+			}
+		}
+		responses.JSONSuccess(c, http.StatusOK, "ok", visible)
+	}
+}
+
+type forkInfo struct {
+	ID          uint      `json:"id"`
+	Owner       string    `json:"owner"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Visibility  string    `json:"visibility"`
+	CreatedAt   time.Time `json:"created_at"`
+}
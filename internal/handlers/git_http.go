@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/middleware"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GitInfoRefs serves the ref advertisement that starts every smart HTTP exchange
+func GitInfoRefs(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service := c.Query("service")
+		if !gitserver.IsValidService(service) {
+			c.String(http.StatusForbidden, "dumb http protocol is not supported\n")
+			return
+		}
+
+		repo, ok := authorizeGitRequest(c, dbConn, service)
+		if !ok {
+			return
+		}
+
+		c.Header("Content-Type", "application/x-"+service+"-advertisement")
+		c.Header("Cache-Control", "no-cache")
+		c.Status(http.StatusOK)
+
+		if err := gitserver.AdvertiseRefs(c.Request.Context(), service, storage.Path(repo.Path), c.GetHeader("Git-Protocol"), c.Writer); err != nil {
+			log.Logger.Error("git ref advertisement failed", zap.String("repo", repo.Path), zap.Error(err))
+		}
+	}
+}
+
+// GitUploadPack serves fetches and clones
+func GitUploadPack(dbConn *db.DB) gin.HandlerFunc {
+	return gitServiceHandler(dbConn, gitserver.UploadPack)
+}
+
+// GitReceivePack serves pushes
+func GitReceivePack(dbConn *db.DB) gin.HandlerFunc {
+	return gitServiceHandler(dbConn, gitserver.ReceivePack)
+}
+
+func gitServiceHandler(dbConn *db.DB, service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, ok := authorizeGitRequest(c, dbConn, service)
+		if !ok {
+			return
+		}
+
+		var body io.Reader = c.Request.Body
+		if c.GetHeader("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(c.Request.Body)
+			if err != nil {
+				c.String(http.StatusBadRequest, "invalid gzip body\n")
+				return
+			}
+			defer gz.Close()
+			body = gz
+		}
+
+		c.Header("Content-Type", "application/x-"+service+"-result")
+		c.Header("Cache-Control", "no-cache")
+		c.Status(http.StatusOK)
+
+		var err error
+		if service == gitserver.ReceivePack {
+			err = gitserver.ServeReceivePack(c.Request.Context(), repo, c.GetUint("user_id"), c.GetHeader("Git-Protocol"), body, c.Writer)
+		} else {
+			err = gitserver.ServeRPC(c.Request.Context(), service, storage.Path(repo.Path), c.GetHeader("Git-Protocol"), body, c.Writer)
+		}
+		if err != nil {
+			log.Logger.Error("git service failed", zap.String("service", service), zap.String("repo", repo.Path), zap.Error(err))
+		}
+	}
+}
+
+// authorizeGitRequest resolves /:owner/:repo and checks the caller may run service on it.
+// Renamed and transferred repositories stay reachable under their old URLs.
+func authorizeGitRequest(c *gin.Context, dbConn *db.DB, service string) (*db.Repository, bool) {
+	repo, _, err := dbConn.ResolveRepository(c.Param("owner"), strings.TrimSuffix(c.Param("repo"), ".git"))
+	if err != nil {
+		c.String(http.StatusNotFound, "repository not found\n")
+		return nil, false
+	}
+
+	var userID uint
+	if v, ok := c.Get("user_id"); ok {
+		userID = v.(uint)
+	}
+
+	scope, allowed := middleware.ScopeRepoRead, repo.CanRead
+	if service == gitserver.ReceivePack {
+		scope, allowed = middleware.ScopeRepoWrite, repo.CanWrite
+	}
+
+	switch {
+	case allowed(0):
+		// public reads need no credentials
+		return repo, true
+	case userID == 0:
+		middleware.RequireGitAuth(c)
+	case !allowed(userID):
+		c.String(http.StatusForbidden, "permission denied\n")
+	case !middleware.HasScope(c, scope):
+		c.String(http.StatusForbidden, "token is missing the "+scope+" scope\n")
+	default:
+		return repo, true
+	}
+	return nil, false
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/policy"
+	"github.com/GordenArcher/mini-github/internal/secrets"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRepoPolicy returns the pre-receive policies of a repository
+func GetRepoPolicy(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		cfg := db.RepositoryPolicy{ForbiddenPaths: []string{}, AuthorEmailDomains: []string{}, SecretScanning: secrets.ModeAlert}
+		if err := dbConn.Where("repository_id = ?", repo.ID).Limit(1).Find(&cfg).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fetch policies")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", cfg)
+	}
+}
+
+// UpdateRepoPolicy replaces the pre-receive policies of a repository
+func UpdateRepoPolicy(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			MaxFileSize          int64    `json:"max_file_size" binding:"min=0"`
+			ForbiddenPaths       []string `json:"forbidden_paths"`
+			CommitMessagePattern string   `json:"commit_message_pattern"`
+			AuthorEmailDomains   []string `json:"author_email_domains"`
+			SecretScanning       string   `json:"secret_scanning"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		for _, p := range payload.ForbiddenPaths {
+			if _, err := path.Match(p, ""); err != nil || p == "" {
+				responses.JSONError(c, http.StatusBadRequest, "invalid forbidden path "+p)
+				return
+			}
+		}
+		if payload.CommitMessagePattern != "" {
+			if _, err := policy.NewCommitMessage(payload.CommitMessagePattern); err != nil {
+				responses.JSONError(c, http.StatusBadRequest, "invalid commit message pattern")
+				return
+			}
+		}
+		for _, d := range payload.AuthorEmailDomains {
+			if d == "" || strings.ContainsAny(d, "@ ") {
+				responses.JSONError(c, http.StatusBadRequest, "invalid email domain "+d)
+				return
+			}
+		}
+
+		if payload.SecretScanning == "" {
+			payload.SecretScanning = secrets.ModeAlert
+		}
+		if !secrets.IsValidMode(payload.SecretScanning) {
+			responses.JSONError(c, http.StatusBadRequest, "secret_scanning must be alert, block or off")
+			return
+		}
+
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		var cfg db.RepositoryPolicy
+		if err := dbConn.Where("repository_id = ?", repo.ID).Limit(1).Find(&cfg).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fetch policies")
+			return
+		}
+		cfg.RepositoryID = repo.ID
+		cfg.MaxFileSize = payload.MaxFileSize
+		cfg.ForbiddenPaths = payload.ForbiddenPaths
+		cfg.CommitMessagePattern = payload.CommitMessagePattern
+		cfg.AuthorEmailDomains = payload.AuthorEmailDomains
+		cfg.SecretScanning = payload.SecretScanning
+		if cfg.ForbiddenPaths == nil {
+			cfg.ForbiddenPaths = []string{}
+		}
+		if cfg.AuthorEmailDomains == nil {
+			cfg.AuthorEmailDomains = []string{}
+		}
+
+		if err := dbConn.Save(&cfg).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save policies")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "policies updated", cfg)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5"
+)
+
+// openRepo loads the repository named by the :owner and :name params, checks
+// the caller may read it and opens its bare Git repository. On failure, or
+// when the repository was renamed or transferred and the client is sent to
+// its new URL, the response has been written.
+func openRepo(c *gin.Context, dbConn *db.DB) (*db.Repository, *git.Repository, bool) {
+	return openRepoWithAccess(c, dbConn, false)
+}
+
+// openWritableRepo is openRepo for requests that modify the repository
+func openWritableRepo(c *gin.Context, dbConn *db.DB) (*db.Repository, *git.Repository, bool) {
+	return openRepoWithAccess(c, dbConn, true)
+}
+
+func openRepoWithAccess(c *gin.Context, dbConn *db.DB, write bool) (*db.Repository, *git.Repository, bool) {
+	repo, moved, err := dbConn.ResolveRepository(c.Param("owner"), c.Param("name"))
+	if err != nil {
+		responses.JSONError(c, http.StatusNotFound, "repo not found")
+		return nil, nil, false
+	}
+
+	var userID uint
+	if v, ok := c.Get("user_id"); ok {
+		userID = v.(uint)
+	}
+	if !repo.CanRead(userID) {
+		responses.JSONError(c, http.StatusUnauthorized, "unauthorized")
+		return nil, nil, false
+	}
+	if moved {
+		redirectToRepo(c, repo)
+		return nil, nil, false
+	}
+	if write && !repo.CanWrite(userID) {
+		responses.JSONError(c, http.StatusForbidden, "you do not have write access to this repository")
+		return nil, nil, false
+	}
+
+	r, err := storage.Open(repo.Path)
+	if err != nil {
+		responses.JSONError(c, http.StatusInternalServerError, "cannot open git repo")
+		return nil, nil, false
+	}
+
+	return repo, r, true
+}
+
+// redirectToRepo sends the client to the same endpoint under the current
+// owner and name of a repository. Other methods than GET keep their method
+// and body.
+func redirectToRepo(c *gin.Context, repo *db.Repository) {
+	from := "/repos/" + c.Param("owner") + "/" + c.Param("name")
+	to := "/repos/" + repo.Owner.Username + "/" + repo.Name
+	u := *c.Request.URL
+	u.Path = strings.Replace(u.Path, from, to, 1)
+	u.RawPath = ""
+
+	code := http.StatusTemporaryRedirect
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	c.Redirect(code, u.RequestURI())
+}
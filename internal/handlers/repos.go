@@ -1,28 +1,36 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strconv"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/forks"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
 	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/GordenArcher/mini-github/internal/webhooks"
 	"github.com/gin-gonic/gin"
-	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"go.uber.org/zap"
 )
 
 // CreateRepo creates a new repository
 
-func CreateRepo(dbConn *db.DB, basePath string) gin.HandlerFunc {
+func CreateRepo(dbConn *db.DB, publicURL, sshPort string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		type payload struct {
-			Name        string `json:"name" binding:"required"`
-			Description string `json:"description"`
-			Visibility  string `json:"visibility"`
+			Name          string `json:"name" binding:"required"`
+			Description   string `json:"description"`
+			Visibility    string `json:"visibility"`
+			DefaultBranch string `json:"default_branch"`
 		}
 
 		var req payload
@@ -31,49 +39,102 @@ func CreateRepo(dbConn *db.DB, basePath string) gin.HandlerFunc {
 			return
 		}
 
+		if err := db.ValidateRepositoryName(req.Name); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Visibility == "" {
+			req.Visibility = "private"
+		}
+		if !validVisibility(req.Visibility) {
+			responses.JSONError(c, http.StatusBadRequest, "visibility must be public or private")
+			return
+		}
+		if req.DefaultBranch == "" {
+			req.DefaultBranch = "main"
+		}
+		if !gitutil.ValidBranchName(req.DefaultBranch) {
+			responses.JSONError(c, 400, "invalid default branch name")
+			return
+		}
+
 		userID := c.MustGet("user_id").(uint)
 
-		// Construct repo path
-		userFolder := strconv.Itoa(int(userID))
-		repoPath := filepath.Join(basePath, userFolder, req.Name+".git")
+		var owner db.User
+		if err := dbConn.First(&owner, userID).Error; err != nil {
+			responses.JSONError(c, http.StatusUnauthorized, "user not found")
+			return
+		}
 
-		// Create directory
-		if err := os.MkdirAll(repoPath, 0755); err != nil {
-			responses.JSONError(c, 500, "failed to create repo folder")
+		if taken, err := dbConn.RepositoryNameTaken(userID, req.Name); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to check repository name")
+			return
+		} else if taken {
+			responses.JSONError(c, http.StatusConflict, "repository already exists")
 			return
 		}
 
 		// Initialize bare repository
-		cmd := exec.Command("git", "init", "--bare")
-		cmd.Dir = repoPath
-		if err := cmd.Run(); err != nil {
+		repoPath := storage.Location(userID, req.Name)
+		if _, err := storage.Create(c.Request.Context(), repoPath, req.DefaultBranch); err != nil {
+			if errors.Is(err, storage.ErrExists) {
+				responses.JSONError(c, http.StatusConflict, "repository already exists")
+				return
+			}
 			responses.JSONError(c, 500, "failed to init bare git repo")
 			return
 		}
 
 		// Save repo in database
 		repo := db.Repository{
-			Name:        req.Name,
-			Description: req.Description,
-			OwnerID:     userID,
-			Visibility:  req.Visibility,
-			Path:        repoPath,
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
+			Name:          req.Name,
+			Description:   req.Description,
+			OwnerID:       userID,
+			Visibility:    req.Visibility,
+			Path:          repoPath,
+			DefaultBranch: req.DefaultBranch,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
 		}
 
-		if err := dbConn.Create(&repo).Error; err != nil {
+		if err := dbConn.CreateRepository(&repo); err != nil {
+			storage.Delete(repoPath)
 			responses.JSONError(c, 500, "failed to save repo")
 			return
 		}
 
+		repo.Owner = owner
+		if err := webhooks.Emit(dbConn, &repo, userID, webhooks.EventRepositoryCreated, nil); err != nil {
+			log.Logger.Error("failed to queue repository_created webhooks", zap.Error(err))
+		}
+
 		responses.JSONSuccess(c, 201, "repository created", gin.H{
 			"repo_name": req.Name,
-			"clone_url": repoPath,
+			"clone_url": cloneURL(publicURL, owner.Username, req.Name),
+			"ssh_url":   sshURL(publicURL, sshPort, owner.Username, req.Name),
 		})
 	}
 }
 
+// validVisibility reports whether v is a visibility CanRead understands
+func validVisibility(v string) bool {
+	return v == "public" || v == "private"
+}
+
+// cloneURL is the smart HTTP address Git clients use for a repository
+func cloneURL(publicURL, owner, name string) string {
+	return fmt.Sprintf("%s/%s/%s.git", publicURL, owner, name)
+}
+
+// sshURL is the SSH address for a repository, served on the same host as publicURL
+func sshURL(publicURL, sshPort, owner, name string) string {
+	host := "localhost"
+	if u, err := url.Parse(publicURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("ssh://git@%s/%s/%s.git", net.JoinHostPort(host, sshPort), owner, name)
+}
+
 // ListUserRepos lists all repositories for the authenticated user
 func ListUserRepos(dbConn *db.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -85,7 +146,8 @@ func ListUserRepos(dbConn *db.DB) gin.HandlerFunc {
 		userID := userIDVal.(uint)
 
 		var repos []db.Repository
-		if err := dbConn.Where("owner_id = ?", userID).Find(&repos).Error; err != nil {
+		// most recently pushed to first
+		if err := dbConn.Where("owner_id = ?", userID).Order("updated_at DESC").Find(&repos).Error; err != nil {
 			responses.JSONError(c, http.StatusInternalServerError, "cannot fetch repos")
 			return
 		}
@@ -94,69 +156,459 @@ func ListUserRepos(dbConn *db.DB) gin.HandlerFunc {
 	}
 }
 
-// GetRepo fetches repository details including commits and files
+// GetRepo fetches repository details with the latest commit and files at HEAD.
+// History is paginated separately by ListRepoCommits.
 func GetRepo(dbConn *db.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		repoID := c.Param("id")
-		var repo db.Repository
-		if err := dbConn.Preload("Owner").First(&repo, repoID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "repo not found"})
+		repo, r, ok := openRepo(c, dbConn)
+		if !ok {
 			return
 		}
 
-		userID, ok := c.Get("user_id")
-		if repo.Visibility == "private" && (!ok || repo.OwnerID != userID.(uint)) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		// an empty repository has no HEAD commit yet
+		var headCommit *gitutil.CommitInfo
+		files := []string{}
+		if ref, err := r.Head(); err == nil {
+			cmt, err := r.CommitObject(ref.Hash())
+			if err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "cannot get HEAD")
+				return
+			}
+			info := gitutil.NewCommitInfo(cmt)
+			headCommit = &info
+
+			// List project files at HEAD, see GetRepoTree for browsing other refs
+			if tree, err := cmt.Tree(); err == nil {
+				_ = tree.Files().ForEach(func(f *object.File) error {
+					files = append(files, f.Name)
+					return nil
+				})
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"repo":        repo,
+			"head_commit": headCommit,
+			"files":       files,
+		})
+	}
+}
+
+// UpdateRepo changes the description, visibility, default branch or name of a
+// repository. Renaming moves its Git data; the old name redirects to the new one.
+func UpdateRepo(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name          *string `json:"name"`
+			Description   *string `json:"description"`
+			Visibility    *string `json:"visibility"`
+			DefaultBranch *string `json:"default_branch"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+		if payload.Name != nil {
+			if err := db.ValidateRepositoryName(*payload.Name); err != nil {
+				responses.JSONError(c, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if payload.Visibility != nil && !validVisibility(*payload.Visibility) {
+			responses.JSONError(c, http.StatusBadRequest, "visibility must be public or private")
+			return
+		}
+		if payload.DefaultBranch != nil && !gitutil.ValidBranchName(*payload.DefaultBranch) {
+			responses.JSONError(c, http.StatusBadRequest, "invalid default branch name")
 			return
 		}
 
-		r, err := git.PlainOpen(repo.Path)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot open git repo"})
+		repo, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
 			return
 		}
 
-		ref, err := r.Head()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot get HEAD"})
+		// check everything before changing anything
+		branch := payload.DefaultBranch
+		if branch != nil && *branch != repo.DefaultBranch {
+			hasBranches, err := gitutil.HasBranches(r)
+			if err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "cannot list branches")
+				return
+			}
+			if _, err := r.Reference(plumbing.NewBranchReferenceName(*branch), false); err != nil && hasBranches {
+				responses.JSONError(c, http.StatusNotFound, "branch not found")
+				return
+			}
+		}
+		rename := payload.Name != nil && *payload.Name != repo.Name
+		// a change of case is a rename too, but the name isn't taken by another repository
+		if rename && !strings.EqualFold(*payload.Name, repo.Name) {
+			if taken, err := dbConn.RepositoryNameTaken(repo.OwnerID, *payload.Name); err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "failed to check repository name")
+				return
+			} else if taken {
+				responses.JSONError(c, http.StatusConflict, "repository already exists")
+				return
+			}
+		}
+
+		// the rename goes first since it's the step that can still conflict,
+		// and nothing else has changed if it does
+		if rename {
+			if err := moveRepo(dbConn, repo, &repo.Owner, *payload.Name); err != nil {
+				if errors.Is(err, storage.ErrExists) {
+					responses.JSONError(c, http.StatusConflict, "another repository is stored under that name")
+					return
+				}
+				log.Logger.Error("failed to rename repository", zap.Uint("repo", repo.ID), zap.Error(err))
+				responses.JSONError(c, http.StatusInternalServerError, "failed to rename repository")
+				return
+			}
+		}
+
+		updates := map[string]any{}
+		if payload.Description != nil {
+			updates["description"] = *payload.Description
+		}
+		if payload.Visibility != nil {
+			updates["visibility"] = *payload.Visibility
+		}
+		if len(updates) > 0 {
+			if err := dbConn.Model(repo).Updates(updates).Error; err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "failed to update repository")
+				return
+			}
+			if payload.Description != nil {
+				repo.Description = *payload.Description
+			}
+			if payload.Visibility != nil {
+				repo.Visibility = *payload.Visibility
+			}
+		}
+		if branch != nil && *branch != repo.DefaultBranch {
+			// the Git data may have just moved with the rename
+			if rename && repo.Path != "" {
+				var err error
+				if r, err = storage.Open(repo.Path); err != nil {
+					responses.JSONError(c, http.StatusInternalServerError, "failed to update default branch")
+					return
+				}
+			}
+			if err := setDefaultBranch(dbConn, repo, r, *branch); err != nil {
+				responses.JSONError(c, http.StatusInternalServerError, "failed to update default branch")
+				return
+			}
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "repository updated", repo)
+	}
+}
+
+// TransferRepo offers a repository to another user. Nothing moves until they
+// accept with AcceptTransfer; a new offer replaces the previous one.
+func TransferRepo(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			NewOwner string `json:"new_owner" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "new_owner required")
 			return
 		}
 
-		iter, err := r.Log(&git.LogOptions{From: ref.Hash()})
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		to, err := dbConn.FindUser(payload.NewOwner)
+		if err != nil || to.Disabled() {
+			responses.JSONError(c, http.StatusNotFound, "user not found")
+			return
+		}
+		if to.ID == repo.OwnerID {
+			responses.JSONError(c, http.StatusUnprocessableEntity, "repository already belongs to "+to.Username)
+			return
+		}
+
+		offer, err := dbConn.OfferTransfer(repo, to)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot get commit logs"})
+			responses.JSONError(c, http.StatusInternalServerError, "failed to offer repository")
 			return
 		}
 
-		var commits []map[string]interface{}
-		_ = iter.ForEach(func(cmt *object.Commit) error {
-			commits = append(commits, map[string]interface{}{
-				"hash":      cmt.Hash.String(),
-				"author":    cmt.Author.Name,
-				"email":     cmt.Author.Email,
-				"message":   cmt.Message,
-				"timestamp": cmt.Author.When,
-			})
-			return nil
+		responses.JSONSuccess(c, http.StatusAccepted, "waiting for "+to.Username+" to accept the transfer", transferInfo{
+			ID:         offer.ID,
+			Repository: repo.Owner.Username + "/" + repo.Name,
+			From:       repo.Owner.Username,
+			To:         to.Username,
+			CreatedAt:  offer.CreatedAt,
 		})
+	}
+}
 
-		// Walk repo files
-		files := []string{}
-		_ = filepath.Walk(repo.Path, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
+// transferInfo describes a transfer offer without the users' private details
+type transferInfo struct {
+	ID         uint      `json:"id"`
+	Repository string    `json:"repository"`
+	From       string    `json:"from"`
+	To         string    `json:"to"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListTransfers lists the transfers offered to the authenticated user, newest first
+func ListTransfers(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		var me db.User
+		if err := dbConn.First(&me, userID).Error; err != nil {
+			responses.JSONError(c, http.StatusUnauthorized, "user not found")
+			return
+		}
+		var offers []db.RepositoryTransfer
+		if err := dbConn.Preload("Repository.Owner").Where("to_id = ?", userID).Order("created_at DESC").Find(&offers).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot fetch transfers")
+			return
+		}
+
+		transfers := []transferInfo{}
+		for _, o := range offers {
+			// a deleted repository can't be taken over
+			if o.Repository.ID == 0 {
+				continue
 			}
-			if !info.IsDir() {
-				rel, _ := filepath.Rel(repo.Path, path)
-				files = append(files, rel)
+			transfers = append(transfers, transferInfo{
+				ID:         o.ID,
+				Repository: o.Repository.Owner.Username + "/" + o.Repository.Name,
+				From:       o.Repository.Owner.Username,
+				To:         me.Username,
+				CreatedAt:  o.CreatedAt,
+			})
+		}
+		responses.JSONSuccess(c, http.StatusOK, "ok", transfers)
+	}
+}
+
+// AcceptTransfer makes the authenticated user the owner of a repository that
+// was offered to them. Its Git data moves and the old owner and name
+// redirect to it.
+func AcceptTransfer(dbConn *db.DB, publicURL, sshPort string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		repo, offer, ok := findTransfer(c, dbConn, "to_id = ?", userID)
+		if !ok {
+			return
+		}
+		var to db.User
+		if err := dbConn.First(&to, offer.ToID).Error; err != nil {
+			responses.JSONError(c, http.StatusUnauthorized, "user not found")
+			return
+		}
+		if taken, err := dbConn.RepositoryNameTaken(to.ID, repo.Name); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to check repository name")
+			return
+		} else if taken {
+			responses.JSONError(c, http.StatusConflict, "you already have a repository named "+repo.Name)
+			return
+		}
+
+		if err := moveRepo(dbConn, repo, &to, repo.Name); err != nil {
+			if errors.Is(err, storage.ErrExists) {
+				responses.JSONError(c, http.StatusConflict, "another repository is stored under that name")
+				return
 			}
-			return nil
+			log.Logger.Error("failed to transfer repository", zap.Uint("repo", repo.ID), zap.Error(err))
+			responses.JSONError(c, http.StatusInternalServerError, "failed to transfer repository")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "repository transferred", gin.H{
+			"owner":     to.Username,
+			"repo_name": repo.Name,
+			"clone_url": cloneURL(publicURL, to.Username, repo.Name),
+			"ssh_url":   sshURL(publicURL, sshPort, to.Username, repo.Name),
 		})
+	}
+}
 
-		c.JSON(http.StatusOK, gin.H{
-			"repo":    repo,
-			"commits": commits,
-			"files":   files,
+// CancelTransfer withdraws a transfer offer, by its owner, or declines it, by
+// the recipient
+func CancelTransfer(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		_, offer, ok := findTransfer(c, dbConn, "from_id = ? OR to_id = ?", userID, userID)
+		if !ok {
+			return
+		}
+		if err := dbConn.Delete(offer).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to cancel transfer")
+			return
+		}
+		responses.JSONSuccess(c, http.StatusOK, "transfer cancelled", nil)
+	}
+}
+
+// findTransfer loads the repository named by the :owner and :name params and
+// its transfer offer, if the offer matches query. The recipient may not be
+// able to read the repository yet, so no access checks apply. On failure the
+// response has been written.
+func findTransfer(c *gin.Context, dbConn *db.DB, query string, args ...any) (*db.Repository, *db.RepositoryTransfer, bool) {
+	repo, _, err := dbConn.ResolveRepository(c.Param("owner"), c.Param("name"))
+	if err != nil {
+		responses.JSONError(c, http.StatusNotFound, "transfer not found")
+		return nil, nil, false
+	}
+	var offer db.RepositoryTransfer
+	if err := dbConn.Where("repository_id = ?", repo.ID).Where(query, args...).First(&offer).Error; err != nil {
+		responses.JSONError(c, http.StatusNotFound, "transfer not found")
+		return nil, nil, false
+	}
+	return repo, &offer, true
+}
+
+// moveRepo renames or transfers a repository, moving its Git data to the
+// location its new owner and name call for
+func moveRepo(dbConn *db.DB, repo *db.Repository, to *db.User, name string) error {
+	// repositories predating storage locations may have nothing on disk to move
+	from, path := repo.Path, ""
+	if from != "" {
+		path = storage.Location(to.ID, name)
+		if err := storage.Move(from, path); err != nil {
+			return err
+		}
+	}
+	if err := dbConn.MoveRepository(repo, to, name, path); err != nil {
+		if from != "" {
+			if moveErr := storage.Move(path, from); moveErr != nil {
+				log.Logger.Error("failed to move repository back", zap.String("from", path), zap.String("to", from), zap.Error(moveErr))
+			}
+		}
+		return err
+	}
+	relinkForks(dbConn, repo)
+	return nil
+}
+
+// relinkForks points the forks of a repository whose Git data moved at its
+// new location. The move itself has succeeded by then, so failures are
+// only logged.
+func relinkForks(dbConn *db.DB, repo *db.Repository) {
+	if err := forks.Relink(dbConn, repo); err != nil {
+		log.Logger.Error("failed to relink forks", zap.Uint("repo", repo.ID), zap.Error(err))
+	}
+}
+
+// DeleteRepo deletes a repository. Until window has passed it can be
+// restored, then a background job purges it with its Git data.
+func DeleteRepo(dbConn *db.DB, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		// the Git data moves aside so the name can be used again
+		from, trash := repo.Path, ""
+		if from != "" {
+			trash = storage.DeletedLocation(repo.ID)
+			if err := storage.Move(from, trash); err != nil {
+				log.Logger.Error("failed to move deleted repository", zap.Uint("repo", repo.ID), zap.Error(err))
+				responses.JSONError(c, http.StatusInternalServerError, "failed to delete repository")
+				return
+			}
+		}
+		if err := dbConn.SoftDeleteRepository(repo, trash); err != nil {
+			if from != "" {
+				storage.Move(trash, from)
+			}
+			responses.JSONError(c, http.StatusInternalServerError, "failed to delete repository")
+			return
+		}
+		relinkForks(dbConn, repo)
+
+		responses.JSONSuccess(c, http.StatusOK, "repository deleted", gin.H{
+			"restore_until": repo.DeletedAt.Time.Add(window),
 		})
 	}
 }
+
+// deletedRepo is a deleted repository and when it will be purged
+type deletedRepo struct {
+	db.Repository
+	RestoreUntil time.Time `json:"restore_until"`
+}
+
+// ListDeletedRepos lists the authenticated user's deleted repositories that can still be restored
+func ListDeletedRepos(dbConn *db.DB, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		var repos []db.Repository
+		err := dbConn.Unscoped().Where("owner_id = ? AND deleted_at > ?", userID, time.Now().Add(-window)).
+			Order("deleted_at DESC").Find(&repos).Error
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot fetch repos")
+			return
+		}
+
+		deleted := make([]deletedRepo, len(repos))
+		for i, r := range repos {
+			deleted[i] = deletedRepo{Repository: r, RestoreUntil: r.DeletedAt.Time.Add(window)}
+		}
+		responses.JSONSuccess(c, http.StatusOK, "ok", deleted)
+	}
+}
+
+// RestoreRepo brings back the most recently deleted repository of the
+// authenticated user under :owner/:name, if it hasn't been purged
+func RestoreRepo(dbConn *db.DB, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		// only owners see their deleted repositories
+		owner, err := dbConn.FindUser(c.Param("owner"))
+		if err != nil || owner.ID != userID {
+			responses.JSONError(c, http.StatusNotFound, "repo not found")
+			return
+		}
+		repo, err := dbConn.FindDeletedRepository(owner.ID, c.Param("name"))
+		if err != nil || repo.DeletedAt.Time.Add(window).Before(time.Now()) {
+			responses.JSONError(c, http.StatusNotFound, "repo not found")
+			return
+		}
+		if taken, err := dbConn.RepositoryNameTaken(owner.ID, repo.Name); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to check repository name")
+			return
+		} else if taken {
+			responses.JSONError(c, http.StatusConflict, "another repository is now named "+repo.Name+", rename it first")
+			return
+		}
+
+		from, path := repo.Path, ""
+		if from != "" {
+			path = storage.Location(owner.ID, repo.Name)
+			if err := storage.Move(from, path); err != nil {
+				if errors.Is(err, storage.ErrExists) {
+					responses.JSONError(c, http.StatusConflict, "another repository is stored under "+repo.Name)
+					return
+				}
+				responses.JSONError(c, http.StatusInternalServerError, "failed to restore repository")
+				return
+			}
+		}
+		if err := dbConn.RestoreRepository(repo, path); err != nil {
+			if from != "" {
+				storage.Move(path, from)
+			}
+			responses.JSONError(c, http.StatusInternalServerError, "failed to restore repository")
+			return
+		}
+		relinkForks(dbConn, repo)
+
+		responses.JSONSuccess(c, http.StatusOK, "repository restored", repo)
+	}
+}
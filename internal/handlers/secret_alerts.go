@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// Secret alert states
+const (
+	alertOpen      = "open"
+	alertDismissed = "dismissed"
+)
+
+// ListSecretAlerts lists the secrets found in a repository, open ones unless ?state= says otherwise
+func ListSecretAlerts(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		query := dbConn.Where("repository_id = ?", repo.ID)
+		switch state := c.DefaultQuery("state", alertOpen); state {
+		case alertOpen, alertDismissed:
+			query = query.Where("state = ?", state)
+		case "all":
+		default:
+			responses.JSONError(c, http.StatusBadRequest, "state must be open, dismissed or all")
+			return
+		}
+
+		alerts := []db.SecretAlert{}
+		if err := query.Order("created_at DESC, id DESC").Find(&alerts).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fetch alerts")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", alerts)
+	}
+}
+
+// UpdateSecretAlert dismisses an alert, or reopens a dismissed one
+func UpdateSecretAlert(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			State  string `json:"state" binding:"required,oneof=open dismissed"`
+			Reason string `json:"reason"` // e.g. revoked, false_positive, used_in_tests
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "state must be open or dismissed")
+			return
+		}
+
+		repo, _, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		var alert db.SecretAlert
+		if err := dbConn.Where("id = ? AND repository_id = ?", c.Param("alert_id"), repo.ID).First(&alert).Error; err != nil {
+			responses.JSONError(c, http.StatusNotFound, "alert not found")
+			return
+		}
+
+		alert.State = payload.State
+		if payload.State == alertDismissed {
+			userID := c.MustGet("user_id").(uint)
+			now := time.Now()
+			alert.DismissedReason = payload.Reason
+			alert.DismissedByID = &userID
+			alert.DismissedAt = &now
+		} else {
+			alert.DismissedReason = ""
+			alert.DismissedByID = nil
+			alert.DismissedAt = nil
+		}
+
+		if err := dbConn.Save(&alert).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to update alert")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "alert updated", alert)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// AddSSHKey registers a public key for Git over SSH
+func AddSSHKey(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Title string `json:"title" binding:"required"`
+			Key   string `json:"key" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		pub, comment, _, _, err := gossh.ParseAuthorizedKey([]byte(payload.Key))
+		if err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid public key")
+			return
+		}
+
+		userID := c.MustGet("user_id").(uint)
+		fingerprint := gossh.FingerprintSHA256(pub)
+
+		var existing db.SSHKey
+		if err := dbConn.Where("fingerprint = ?", fingerprint).First(&existing).Error; err == nil {
+			responses.JSONError(c, http.StatusConflict, "key is already in use")
+			return
+		}
+
+		// store the key in canonical authorized_keys form, keeping the comment
+		publicKey := strings.TrimSpace(string(gossh.MarshalAuthorizedKey(pub)))
+		if comment != "" {
+			publicKey += " " + comment
+		}
+
+		key := db.SSHKey{
+			UserID:      userID,
+			Title:       payload.Title,
+			PublicKey:   publicKey,
+			Fingerprint: fingerprint,
+		}
+		if err := dbConn.Create(&key).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save key")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusCreated, "key added", key)
+	}
+}
+
+// ListSSHKeys lists the authenticated user's SSH keys
+func ListSSHKeys(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		var keys []db.SSHKey
+		if err := dbConn.Where("user_id = ?", userID).Order("created_at").Find(&keys).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot fetch keys")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", keys)
+	}
+}
+
+// DeleteSSHKey removes one of the authenticated user's SSH keys
+func DeleteSSHKey(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		res := dbConn.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&db.SSHKey{})
+		if res.Error != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to delete key")
+			return
+		}
+		if res.RowsAffected == 0 {
+			responses.JSONError(c, http.StatusNotFound, "key not found")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "key deleted", nil)
+	}
+}
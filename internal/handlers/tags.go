@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type tagInfo struct {
+	Name      string             `json:"name"`
+	SHA       string             `json:"sha"` // the tag object for annotated tags, otherwise the commit
+	Annotated bool               `json:"annotated"`
+	Tagger    *gitutil.Signature `json:"tagger,omitempty"`
+	Message   string             `json:"message,omitempty"`
+	Commit    gitutil.CommitInfo `json:"commit"`
+}
+
+// date orders tags, newest first
+func (t tagInfo) date() time.Time {
+	if t.Tagger != nil {
+		return t.Tagger.Date
+	}
+	return t.Commit.Committer.Date
+}
+
+// ListTags lists lightweight and annotated tags with the commit they point to
+func ListTags(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		iter, err := r.Tags()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot list tags")
+			return
+		}
+		defer iter.Close()
+
+		tags := []tagInfo{}
+		err = iter.ForEach(func(ref *plumbing.Reference) error {
+			t, err := newTagInfo(r, ref)
+			if err != nil {
+				// tags pointing at trees or blobs aren't listed
+				return nil
+			}
+			tags = append(tags, t)
+			return nil
+		})
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot list tags")
+			return
+		}
+
+		sort.SliceStable(tags, func(i, j int) bool { return tags[i].date().After(tags[j].date()) })
+		responses.JSONSuccess(c, http.StatusOK, "ok", tags)
+	}
+}
+
+// CreateTag tags a ref. Tags with a message are annotated and record the caller as tagger.
+func CreateTag(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name    string `json:"name" binding:"required"`
+			Target  string `json:"target"`
+			Message string `json:"message"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+		if !gitutil.ValidTagName(payload.Name) {
+			responses.JSONError(c, http.StatusBadRequest, "invalid tag name")
+			return
+		}
+
+		repo, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		if payload.Target == "" {
+			payload.Target = repo.DefaultBranch
+		}
+		target, err := gitutil.ResolveCommit(r, payload.Target)
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "target not found")
+			return
+		}
+
+		var opts *git.CreateTagOptions
+		if strings.TrimSpace(payload.Message) != "" {
+			var user db.User
+			if err := dbConn.First(&user, c.MustGet("user_id").(uint)).Error; err != nil {
+				responses.JSONError(c, http.StatusUnauthorized, "user not found")
+				return
+			}
+			name := user.DisplayName
+			if name == "" {
+				name = user.Username
+			}
+			opts = &git.CreateTagOptions{
+				Tagger:  &object.Signature{Name: name, Email: user.Email, When: time.Now()},
+				Message: payload.Message,
+			}
+		}
+
+		ref, err := r.CreateTag(payload.Name, target.Hash, opts)
+		if err == git.ErrTagExists {
+			responses.JSONError(c, http.StatusConflict, "tag already exists")
+			return
+		}
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to create tag")
+			return
+		}
+
+		t, err := newTagInfo(r, ref)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to read tag")
+			return
+		}
+		responses.JSONSuccess(c, http.StatusCreated, "tag created", t)
+	}
+}
+
+// DeleteTag deletes a tag
+func DeleteTag(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, r, ok := openWritableRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		err := r.DeleteTag(strings.TrimPrefix(c.Param("tag"), "/"))
+		if err == git.ErrTagNotFound {
+			responses.JSONError(c, http.StatusNotFound, "tag not found")
+			return
+		}
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to delete tag")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "tag deleted", nil)
+	}
+}
+
+func newTagInfo(r *git.Repository, ref *plumbing.Reference) (tagInfo, error) {
+	t := tagInfo{Name: ref.Name().Short(), SHA: ref.Hash().String()}
+
+	if tag, err := r.TagObject(ref.Hash()); err == nil {
+		tagger := gitutil.Signature{Name: tag.Tagger.Name, Email: tag.Tagger.Email, Date: tag.Tagger.When}
+		t.Annotated, t.Tagger, t.Message = true, &tagger, tag.Message
+	}
+
+	cmt, err := gitutil.PeelToCommit(r, ref.Hash())
+	if err != nil {
+		return tagInfo{}, err
+	}
+	t.Commit = gitutil.NewCommitInfo(cmt)
+	return t, nil
+}
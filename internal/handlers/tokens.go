@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/helper/utils/tokens"
+	"github.com/GordenArcher/mini-github/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePersonalAccessToken issues a long-lived token for scripts and Git credential helpers.
+// The token is only ever returned by this call.
+func CreatePersonalAccessToken(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload struct {
+			Name      string     `json:"name" binding:"required"`
+			Scopes    []string   `json:"scopes" binding:"required,min=1"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		for _, scope := range payload.Scopes {
+			if !middleware.IsValidScope(scope) {
+				responses.JSONError(c, http.StatusBadRequest, "unknown scope "+scope)
+				return
+			}
+			// a token can't mint a token with more access than it has itself
+			if !middleware.HasScope(c, scope) {
+				responses.JSONError(c, http.StatusForbidden, "token can't grant the "+scope+" scope it doesn't have")
+				return
+			}
+		}
+		if payload.ExpiresAt != nil && payload.ExpiresAt.Before(time.Now()) {
+			responses.JSONError(c, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+
+		token, hash, err := tokens.Generate()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to generate token")
+			return
+		}
+
+		slices.Sort(payload.Scopes)
+		pat := db.PersonalAccessToken{
+			UserID:      c.MustGet("user_id").(uint),
+			Name:        payload.Name,
+			TokenHash:   hash,
+			TokenPrefix: token[:len(tokens.Prefix)+8],
+			Scopes:      slices.Compact(payload.Scopes),
+			ExpiresAt:   payload.ExpiresAt,
+		}
+		if err := dbConn.Create(&pat).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save token")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusCreated, "token created", gin.H{
+			"token":   token,
+			"details": pat,
+		})
+	}
+}
+
+// ListPersonalAccessTokens lists the authenticated user's tokens without their secrets
+func ListPersonalAccessTokens(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		var pats []db.PersonalAccessToken
+		if err := dbConn.Where("user_id = ?", userID).Order("created_at").Find(&pats).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot fetch tokens")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", pats)
+	}
+}
+
+// RevokePersonalAccessToken deletes one of the authenticated user's tokens
+func RevokePersonalAccessToken(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("user_id").(uint)
+
+		res := dbConn.Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&db.PersonalAccessToken{})
+		if res.Error != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to revoke token")
+			return
+		}
+		if res.RowsAffected == 0 {
+			responses.JSONError(c, http.StatusNotFound, "token not found")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "token revoked", nil)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type treeEntry struct {
+	Name       string              `json:"name"`
+	Path       string              `json:"path"`
+	Type       string              `json:"type"` // blob, tree or commit (submodule)
+	Mode       string              `json:"mode"`
+	SHA        string              `json:"sha"`
+	Size       int64               `json:"size,omitempty"`
+	LastCommit *gitutil.CommitInfo `json:"last_commit,omitempty"`
+}
+
+// GetRepoTree lists the entries of a directory at a branch, tag or commit
+func GetRepoTree(dbConn *db.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, r, ok := openRepo(c, dbConn)
+		if !ok {
+			return
+		}
+
+		commit, ref, dir, err := gitutil.ResolveRefAndPath(r, c.Param("ref")+c.Param("path"))
+		if err != nil {
+			responses.JSONError(c, http.StatusNotFound, "ref not found")
+			return
+		}
+
+		tree, err := commit.Tree()
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot read tree")
+			return
+		}
+		if dir != "" {
+			if tree, err = tree.Tree(dir); err != nil {
+				if errors.Is(err, object.ErrDirectoryNotFound) {
+					responses.JSONError(c, http.StatusNotFound, "directory not found")
+					return
+				}
+				responses.JSONError(c, http.StatusInternalServerError, "cannot read tree")
+				return
+			}
+		}
+
+		names := make([]string, 0, len(tree.Entries))
+		for _, e := range tree.Entries {
+			names = append(names, e.Name)
+		}
+		lastCommits, err := gitutil.LastCommits(r, commit, dir, names)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "cannot read history")
+			return
+		}
+
+		entries := make([]treeEntry, 0, len(tree.Entries))
+		for _, e := range tree.Entries {
+			entry := treeEntry{
+				Name: e.Name,
+				Path: path.Join(dir, e.Name),
+				Type: entryType(e.Mode),
+				Mode: fmt.Sprintf("%06o", uint32(e.Mode)),
+				SHA:  e.Hash.String(),
+			}
+			if entry.Type == "blob" {
+				if size, err := tree.Size(e.Name); err == nil {
+					entry.Size = size
+				}
+			}
+			if lc, ok := lastCommits[e.Name]; ok {
+				info := gitutil.NewCommitInfo(lc)
+				entry.LastCommit = &info
+			}
+			entries = append(entries, entry)
+		}
+
+		// directories first, like every file browser
+		sort.SliceStable(entries, func(i, j int) bool {
+			if (entries[i].Type == "tree") != (entries[j].Type == "tree") {
+				return entries[i].Type == "tree"
+			}
+			return entries[i].Name < entries[j].Name
+		})
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+			"ref":     ref,
+			"commit":  commit.Hash.String(),
+			"path":    dir,
+			"entries": entries,
+		})
+	}
+}
+
+func entryType(mode filemode.FileMode) string {
+	switch mode {
+	case filemode.Dir:
+		return "tree"
+	case filemode.Submodule:
+		return "commit"
+	default:
+		return "blob"
+	}
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/webhooks"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// webhookScope is whose hooks a request manages: a repository's or, with a
+// nil repoID, those covering all of an owner's repositories
+type webhookScope struct {
+	ownerID uint
+	repoID  *uint
+}
+
+// WebhookScopeFunc resolves the scope of a request. On failure the response has been written.
+type WebhookScopeFunc func(c *gin.Context, dbConn *db.DB) (webhookScope, bool)
+
+// RepoWebhooks scopes webhook requests to the repository named by :owner/:name
+func RepoWebhooks(c *gin.Context, dbConn *db.DB) (webhookScope, bool) {
+	repo, _, ok := openWritableRepo(c, dbConn)
+	if !ok {
+		return webhookScope{}, false
+	}
+	return webhookScope{ownerID: repo.OwnerID, repoID: &repo.ID}, true
+}
+
+// UserWebhooks scopes webhook requests to the hooks on all of the caller's repositories
+func UserWebhooks(c *gin.Context, dbConn *db.DB) (webhookScope, bool) {
+	return webhookScope{ownerID: c.MustGet("user_id").(uint)}, true
+}
+
+func (s webhookScope) hooks(dbConn *db.DB) *gorm.DB {
+	q := dbConn.Where("owner_id = ?", s.ownerID)
+	if s.repoID == nil {
+		return q.Where("repository_id IS NULL")
+	}
+	return q.Where("repository_id = ?", *s.repoID)
+}
+
+// findWebhook loads the :hook_id hook within the request's scope. On failure the response has been written.
+func findWebhook(c *gin.Context, dbConn *db.DB, scope WebhookScopeFunc) (*db.Webhook, bool) {
+	s, ok := scope(c, dbConn)
+	if !ok {
+		return nil, false
+	}
+	var hook db.Webhook
+	if err := s.hooks(dbConn).Where("id = ?", c.Param("hook_id")).First(&hook).Error; err != nil {
+		responses.JSONError(c, http.StatusNotFound, "webhook not found")
+		return nil, false
+	}
+	return &hook, true
+}
+
+type webhookPayload struct {
+	URL    *string  `json:"url"`
+	Secret *string  `json:"secret"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// apply validates payload and copies the fields it sets onto hook. On failure the response has been written.
+func (p webhookPayload) apply(c *gin.Context, hook *db.Webhook) bool {
+	if p.URL != nil {
+		u, err := url.Parse(*p.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			responses.JSONError(c, http.StatusBadRequest, "url must be an http or https URL")
+			return false
+		}
+		hook.URL = *p.URL
+	}
+	if p.Secret != nil {
+		hook.Secret = *p.Secret
+	}
+	if p.Events != nil {
+		if len(p.Events) == 0 {
+			responses.JSONError(c, http.StatusBadRequest, "events must not be empty")
+			return false
+		}
+		for _, e := range p.Events {
+			if !webhooks.IsValidEvent(e) {
+				responses.JSONError(c, http.StatusBadRequest, "unknown event "+e)
+				return false
+			}
+		}
+		hook.Events = p.Events
+	}
+	if p.Active != nil {
+		hook.Active = *p.Active
+	}
+	return true
+}
+
+// ListWebhooks lists the webhooks in scope
+func ListWebhooks(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, ok := scope(c, dbConn)
+		if !ok {
+			return
+		}
+
+		hooks := []db.Webhook{}
+		if err := s.hooks(dbConn).Order("id").Find(&hooks).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fetch webhooks")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", hooks)
+	}
+}
+
+// CreateWebhook registers a webhook and sends it a ping
+func CreateWebhook(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload webhookPayload
+		if err := c.ShouldBindJSON(&payload); err != nil || payload.URL == nil {
+			responses.JSONError(c, http.StatusBadRequest, "url is required")
+			return
+		}
+
+		s, ok := scope(c, dbConn)
+		if !ok {
+			return
+		}
+
+		hook := db.Webhook{OwnerID: s.ownerID, RepositoryID: s.repoID, Events: []string{webhooks.EventPush}, Active: true}
+		if !payload.apply(c, &hook) {
+			return
+		}
+		if err := dbConn.Create(&hook).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save webhook")
+			return
+		}
+		if _, err := webhooks.Ping(dbConn, &hook); err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to queue ping")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusCreated, "webhook created", hook)
+	}
+}
+
+// GetWebhook returns a webhook
+func GetWebhook(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := findWebhook(c, dbConn, scope)
+		if !ok {
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", hook)
+	}
+}
+
+// UpdateWebhook changes the fields of a webhook present in the payload
+func UpdateWebhook(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payload webhookPayload
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			responses.JSONError(c, http.StatusBadRequest, "invalid payload")
+			return
+		}
+
+		hook, ok := findWebhook(c, dbConn, scope)
+		if !ok {
+			return
+		}
+		if !payload.apply(c, hook) {
+			return
+		}
+		if err := dbConn.Save(hook).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to save webhook")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "webhook updated", hook)
+	}
+}
+
+// DeleteWebhook removes a webhook and its delivery history
+func DeleteWebhook(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := findWebhook(c, dbConn, scope)
+		if !ok {
+			return
+		}
+
+		err := dbConn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("webhook_id = ?", hook.ID).Delete(&db.WebhookDelivery{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(hook).Error
+		})
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to delete webhook")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "webhook deleted", nil)
+	}
+}
+
+// PingWebhook queues a ping to a webhook
+func PingWebhook(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hook, ok := findWebhook(c, dbConn, scope)
+		if !ok {
+			return
+		}
+
+		d, err := webhooks.Ping(dbConn, hook)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to queue ping")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusAccepted, "ping queued", d)
+	}
+}
+
+// ListWebhookDeliveries lists a webhook's deliveries, newest first.
+// Pass the returned next_cursor as ?cursor= to fetch the following page.
+func ListWebhookDeliveries(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		perPage, err := perPageParam(c)
+		if err != nil {
+			responses.JSONError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		hook, ok := findWebhook(c, dbConn, scope)
+		if !ok {
+			return
+		}
+
+		query := dbConn.Where("webhook_id = ?", hook.ID)
+		if v := c.Query("cursor"); v != "" {
+			cursor, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				responses.JSONError(c, http.StatusBadRequest, "invalid cursor")
+				return
+			}
+			query = query.Where("id <= ?", cursor)
+		}
+
+		deliveries := []db.WebhookDelivery{}
+		if err := query.Order("id DESC").Limit(perPage + 1).Find(&deliveries).Error; err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to fetch deliveries")
+			return
+		}
+
+		// the extra delivery starts the next page
+		var nextCursor string
+		if len(deliveries) > perPage {
+			nextCursor = strconv.FormatUint(uint64(deliveries[perPage].ID), 10)
+			deliveries = deliveries[:perPage]
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", gin.H{
+			"deliveries":  deliveries,
+			"next_cursor": nextCursor,
+		})
+	}
+}
+
+// GetWebhookDelivery returns one delivery with its request and response
+func GetWebhookDelivery(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, ok := findWebhookDelivery(c, dbConn, scope)
+		if !ok {
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusOK, "ok", d)
+	}
+}
+
+// RedeliverWebhook queues a new delivery of an earlier delivery's payload
+func RedeliverWebhook(dbConn *db.DB, scope WebhookScopeFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		previous, ok := findWebhookDelivery(c, dbConn, scope)
+		if !ok {
+			return
+		}
+
+		d, err := webhooks.Redeliver(dbConn, previous)
+		if err != nil {
+			responses.JSONError(c, http.StatusInternalServerError, "failed to queue redelivery")
+			return
+		}
+
+		responses.JSONSuccess(c, http.StatusAccepted, "redelivery queued", d)
+	}
+}
+
+func findWebhookDelivery(c *gin.Context, dbConn *db.DB, scope WebhookScopeFunc) (*db.WebhookDelivery, bool) {
+	hook, ok := findWebhook(c, dbConn, scope)
+	if !ok {
+		return nil, false
+	}
+	var d db.WebhookDelivery
+	if err := dbConn.Where("id = ? AND webhook_id = ?", c.Param("delivery_id"), hook.ID).First(&d).Error; err != nil {
+		responses.JSONError(c, http.StatusNotFound, "delivery not found")
+		return nil, false
+	}
+	return &d, true
+}
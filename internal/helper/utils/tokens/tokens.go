@@ -0,0 +1,33 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks personal access tokens so they can be told apart from JWTs
+const Prefix = "mgh_"
+
+// Generate returns a new personal access token and the hash to store for it
+func Generate() (token, hash string, err error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = Prefix + hex.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// Hash returns the value stored in the database for token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsPersonalAccessToken reports whether token looks like one we issued
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
@@ -26,17 +26,12 @@ func SendVerificationEmail(user *db.User, mailer *mail.Mailer) error {
 		return fmt.Errorf("failed to store token: %w", err)
 	}
 
-	link := fmt.Sprintf("http://localhost:8080/api/v1/auth/verify?token=%s", verif)
-	body := fmt.Sprintf("Please verify your email by clicking <a href=\"%s\">here</a>", link)
-
 	if mailer != nil {
-		go func() {
-			if err := mailer.Send(user.Email, "Verify your account", body); err != nil {
-				log.Logger.Error("failed to send verification email", zap.Error(err))
-			} else {
-				log.Logger.Info("verification email sent", zap.String("to", user.Email))
-			}
-		}()
+		data := map[string]any{"Username": user.Username, "Token": verif}
+		if err := mailer.EnqueueTemplate(redis.Ctx, user.Email, user.Locale, "verify_email", data); err != nil {
+			return fmt.Errorf("failed to queue email: %w", err)
+		}
+		log.Logger.Info("verification email queued", zap.String("to", user.Email))
 	}
 
 	return nil
@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Queues, polled by workers in this order
+const (
+	QueueMail        = "mail"
+	QueueWebhooks    = "webhooks"
+	QueueMaintenance = "maintenance"
+)
+
+const (
+	// DefaultMaxAttempts is how many times a job runs before it is dead-lettered
+	DefaultMaxAttempts = 5
+
+	retryBase = 15 * time.Second
+	retryMax  = time.Hour
+	// dead-letter lists keep this many of the most recent jobs per queue
+	maxDeadJobs = 1000
+)
+
+// Redis keys. Ready jobs wait in a list per queue; jobs due later, including
+// retries, wait in one sorted set scored by run time; running jobs sit in
+// another scored by the end of their lease.
+const (
+	keyPrefix    = "jobs:"
+	keyQueue     = keyPrefix + "queue:"
+	keyDead      = keyPrefix + "dead:"
+	keyScheduled = keyPrefix + "scheduled"
+	keyInflight  = keyPrefix + "inflight"
+	keyPeriodic  = keyPrefix + "periodic:"
+)
+
+// Job is a unit of background work as stored in Redis
+type Job struct {
+	ID          string          `json:"id"`
+	Queue       string          `json:"queue"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+
+	// raw is the stored form, needed to remove the job from the in-flight set
+	raw string
+}
+
+// Decode unmarshals the job's payload into v
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Handler runs a job. A returned error schedules a retry.
+type Handler func(ctx context.Context, job *Job) error
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string]Handler{}
+)
+
+// Register sets the handler for jobs of the given type
+func Register(jobType string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[jobType] = h
+}
+
+func handlerFor(jobType string) Handler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return handlers[jobType]
+}
+
+// Enqueue adds a job to run as soon as a worker is free
+func Enqueue(ctx context.Context, queue, jobType string, payload any) error {
+	j, err := newJob(queue, jobType, payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return redis.Client.LPush(ctx, keyQueue+queue, data).Err()
+}
+
+// Schedule adds a job to run once at is reached
+func Schedule(ctx context.Context, queue, jobType string, payload any, at time.Time) error {
+	j, err := newJob(queue, jobType, payload)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return redis.Client.ZAdd(ctx, keyScheduled, goredis.Z{Score: float64(at.UnixMilli()), Member: data}).Err()
+}
+
+// DeadJobs returns up to n of the most recent jobs on queue that ran out of attempts
+func DeadJobs(ctx context.Context, queue string, n int) ([]Job, error) {
+	raw, err := redis.Client.LRange(ctx, keyDead+queue, 0, int64(n)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(raw))
+	for _, r := range raw {
+		var j Job
+		if err := json.Unmarshal([]byte(r), &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// Backoff is how long to wait before retrying after the given failed attempt
+func Backoff(attempt int) time.Duration {
+	d := retryBase << (attempt - 1)
+	if d > retryMax || d <= 0 {
+		return retryMax
+	}
+	return d
+}
+
+func newJob(queue, jobType string, payload any) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s payload: %w", jobType, err)
+	}
+	id := make([]byte, 12)
+	rand.Read(id)
+	return &Job{
+		ID:          hex.EncodeToString(id),
+		Queue:       queue,
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: DefaultMaxAttempts,
+		EnqueuedAt:  time.Now(),
+	}, nil
+}
@@ -0,0 +1,244 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/redis"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	pollInterval     = 500 * time.Millisecond
+	scheduleInterval = time.Second
+	// a claimed job goes back on its queue if not finished within the lease,
+	// which covers workers that die mid-job
+	lease      = 10 * time.Minute
+	jobTimeout = 5 * time.Minute
+	// how many due jobs one scheduler tick moves onto their queues
+	promoteBatch = 100
+)
+
+// claimScript pops the oldest ready job and records it as in flight
+var claimScript = goredis.NewScript(`
+local m = redis.call('RPOP', KEYS[1])
+if m then redis.call('ZADD', KEYS[2], ARGV[1], m) end
+return m
+`)
+
+// promoteScript moves scheduled jobs that are due onto their queues
+var promoteScript = goredis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, m in ipairs(due) do
+	redis.call('ZREM', KEYS[1], m)
+	redis.call('LPUSH', ARGV[3] .. cjson.decode(m).queue, m)
+end
+return #due
+`)
+
+// reapScript requeues in-flight jobs whose lease ran out, counting it as a failed attempt
+var reapScript = goredis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, m in ipairs(expired) do
+	redis.call('ZREM', KEYS[1], m)
+	local j = cjson.decode(m)
+	j.attempts = j.attempts + 1
+	j.last_error = 'lease expired'
+	if j.attempts >= j.max_attempts then
+		redis.call('LPUSH', ARGV[4] .. j.queue, cjson.encode(j))
+		redis.call('LTRIM', ARGV[4] .. j.queue, 0, ARGV[5] - 1)
+	else
+		redis.call('LPUSH', ARGV[3] .. j.queue, cjson.encode(j))
+	end
+end
+return #expired
+`)
+
+type periodic struct {
+	interval time.Duration
+	queue    string
+	jobType  string
+	payload  any
+}
+
+var schedules []periodic
+
+// Every enqueues a job once per interval. With several servers running,
+// the job is still enqueued only once per interval.
+func Every(interval time.Duration, queue, jobType string, payload any) {
+	mu.Lock()
+	defer mu.Unlock()
+	schedules = append(schedules, periodic{interval, queue, jobType, payload})
+}
+
+// Pool runs jobs from a set of queues with a fixed number of workers
+type Pool struct {
+	workers int
+	queues  []string
+}
+
+// NewPool creates a pool of workers taking jobs from queues, earlier queues first
+func NewPool(workers int, queues ...string) *Pool {
+	return &Pool{workers: workers, queues: queues}
+}
+
+// Run works through jobs until ctx is cancelled, then waits for running jobs to finish
+func (p *Pool) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(p.workers + 1)
+	go func() {
+		defer wg.Done()
+		p.schedule(ctx)
+	}()
+	for i := 0; i < p.workers; i++ {
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// schedule moves due and expired jobs onto their queues and fires periodic jobs
+func (p *Pool) schedule(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		ms := strconv.FormatInt(now.UnixMilli(), 10)
+		if err := promoteScript.Run(ctx, redis.Client, []string{keyScheduled}, ms, promoteBatch, keyQueue).Err(); err != nil && ctx.Err() == nil {
+			log.Logger.Error("promoting scheduled jobs failed", zap.Error(err))
+		}
+		if err := reapScript.Run(ctx, redis.Client, []string{keyInflight}, ms, promoteBatch, keyQueue, keyDead, maxDeadJobs).Err(); err != nil && ctx.Err() == nil {
+			log.Logger.Error("requeueing expired jobs failed", zap.Error(err))
+		}
+		firePeriodic(ctx, now)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func firePeriodic(ctx context.Context, now time.Time) {
+	mu.RLock()
+	due := schedules
+	mu.RUnlock()
+
+	for _, s := range due {
+		// the first server to claim this interval's slot enqueues the job
+		slot := now.UnixNano() / int64(s.interval)
+		key := fmt.Sprintf("%s%s:%d", keyPeriodic, s.jobType, slot)
+		ok, err := redis.Client.SetNX(ctx, key, "1", 2*s.interval).Result()
+		if err == nil && ok {
+			err = Enqueue(ctx, s.queue, s.jobType, s.payload)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Logger.Error("enqueueing periodic job failed", zap.String("type", s.jobType), zap.Error(err))
+		}
+	}
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		j, err := p.claim(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Logger.Error("claiming job failed", zap.Error(err))
+		}
+		if j == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		// a job already started finishes even while shutting down
+		runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobTimeout)
+		err = run(runCtx, j)
+		cancel()
+		if err := finish(context.WithoutCancel(ctx), j, err); err != nil {
+			log.Logger.Error("recording job result failed", zap.String("id", j.ID), zap.Error(err))
+		}
+	}
+}
+
+// claim takes the next ready job from the pool's queues, or returns nil if they are empty
+func (p *Pool) claim(ctx context.Context) (*Job, error) {
+	deadline := time.Now().Add(lease).UnixMilli()
+	for _, q := range p.queues {
+		raw, err := claimScript.Run(ctx, redis.Client, []string{keyQueue + q, keyInflight}, deadline).Text()
+		if err == goredis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var j Job
+		if err := json.Unmarshal([]byte(raw), &j); err != nil {
+			redis.Client.ZRem(ctx, keyInflight, raw)
+			return nil, fmt.Errorf("dropping malformed job: %w", err)
+		}
+		j.raw = raw
+		return &j, nil
+	}
+	return nil, nil
+}
+
+func run(ctx context.Context, j *Job) (err error) {
+	h := handlerFor(j.Type)
+	if h == nil {
+		return fmt.Errorf("no handler for job type %s", j.Type)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, j)
+}
+
+// finish removes a job from the in-flight set and, if it failed, schedules a
+// retry or dead-letters it
+func finish(ctx context.Context, j *Job, jobErr error) error {
+	if jobErr == nil {
+		return redis.Client.ZRem(ctx, keyInflight, j.raw).Err()
+	}
+
+	j.Attempts++
+	j.LastError = jobErr.Error()
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	dead := j.Attempts >= j.MaxAttempts
+	if dead {
+		log.Logger.Error("job failed permanently", zap.String("id", j.ID), zap.String("type", j.Type), zap.Error(jobErr))
+	} else {
+		log.Logger.Warn("job failed, will retry", zap.String("id", j.ID), zap.String("type", j.Type), zap.Int("attempt", j.Attempts), zap.Error(jobErr))
+	}
+
+	_, err = redis.Client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.ZRem(ctx, keyInflight, j.raw)
+		if dead {
+			pipe.LPush(ctx, keyDead+j.Queue, data)
+			pipe.LTrim(ctx, keyDead+j.Queue, 0, maxDeadJobs-1)
+		} else {
+			at := time.Now().Add(Backoff(j.Attempts))
+			pipe.ZAdd(ctx, keyScheduled, goredis.Z{Score: float64(at.UnixMilli()), Member: data})
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/GordenArcher/mini-github/internal/jobs"
+)
+
+// JobSend is the job type that delivers queued mail
+const JobSend = "mail.send"
+
+// Enqueue queues a message for the job workers, which retry it while the SMTP server is unreachable
+func (m *Mailer) Enqueue(ctx context.Context, msg *Message) error {
+	return jobs.Enqueue(ctx, jobs.QueueMail, JobSend, msg)
+}
+
+// EnqueueTemplate renders the named template for a recipient and queues the result
+func (m *Mailer) EnqueueTemplate(ctx context.Context, to, locale, name string, data map[string]any) error {
+	msg, err := m.templates.Render(name, locale, data)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return m.Enqueue(ctx, msg)
+}
+
+// HandleSend is the job handler that sends an enqueued message
+func (m *Mailer) HandleSend(ctx context.Context, j *jobs.Job) error {
+	var msg Message
+	if err := j.Decode(&msg); err != nil {
+		return err
+	}
+	return m.Send(&msg)
+}
@@ -1,29 +1,46 @@
 package mail
 
 import (
-	"strconv"
+	"io"
 
 	"gopkg.in/gomail.v2"
 )
 
+// Message is an email with plain text and HTML alternatives
+type Message struct {
+	From    string `json:"from,omitempty"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// WriteTo writes msg as a MIME message, as sent over SMTP or saved to a .eml file
+func (msg *Message) WriteTo(w io.Writer) (int64, error) {
+	gm := gomail.NewMessage()
+	gm.SetHeader("From", msg.From)
+	gm.SetHeader("To", msg.To)
+	gm.SetHeader("Subject", msg.Subject)
+	// clients show the last alternative they support, so HTML goes last
+	gm.SetBody("text/plain", msg.Text)
+	gm.AddAlternative("text/html", msg.HTML)
+	return gm.WriteTo(w)
+}
+
 type Mailer struct {
-	host string
-	port int
-	user string
-	pass string
+	from      string
+	transport Transport
+	templates *Templates
 }
 
-func New(host, portStr, user, pass string) *Mailer {
-	port, _ := strconv.Atoi(portStr)
-	return &Mailer{host: host, port: port, user: user, pass: pass}
+// New creates a mailer sending from the given address through transport
+func New(from string, transport Transport, templates *Templates) *Mailer {
+	return &Mailer{from: from, transport: transport, templates: templates}
 }
 
-func (m *Mailer) Send(to, subject, body string) error {
-	d := gomail.NewDialer(m.host, m.port, m.user, m.pass)
-	msg := gomail.NewMessage()
-	msg.SetHeader("From", m.user)
-	msg.SetHeader("To", to)
-	msg.SetHeader("Subject", subject)
-	msg.SetBody("text/html", body)
-	return d.DialAndSend(msg)
+func (m *Mailer) Send(msg *Message) error {
+	if msg.From == "" {
+		msg.From = m.from
+	}
+	return m.transport.Send(msg)
 }
@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used when a template has no variant for the recipient's locale
+const DefaultLocale = "en"
+
+//go:embed templates
+var builtin embed.FS
+
+// Templates renders emails from a layout and a per-email template, each as
+// HTML and plain text. Files live in one directory per locale:
+//
+//	en/layout.html  en/layout.txt
+//	en/verify_email.html  en/verify_email.txt
+//
+// The plain text template defines the subject with {{define "subject"}}.
+// Files in the override directory take precedence over the built-in ones,
+// and are read on every render so edits apply without a restart.
+type Templates struct {
+	files   fs.FS
+	baseURL string
+}
+
+// NewTemplates creates templates whose links point at baseURL. dir, if not
+// empty, holds overrides laid out like the built-in templates.
+func NewTemplates(dir, baseURL string) *Templates {
+	embedded, _ := fs.Sub(builtin, "templates")
+	files := overlayFS{embedded}
+	if dir != "" {
+		files = overlayFS{os.DirFS(dir), embedded}
+	}
+	return &Templates{files: files, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Render builds the message for the named template in locale. Templates see
+// data plus BaseURL, the public address of the server.
+func (t *Templates) Render(name, locale string, data map[string]any) (*Message, error) {
+	vars := map[string]any{"BaseURL": t.baseURL}
+	for k, v := range data {
+		vars[k] = v
+	}
+
+	textLayout, err := t.read("layout.txt", locale)
+	if err != nil {
+		return nil, err
+	}
+	textBody, err := t.read(name+".txt", locale)
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New("layout").Option("missingkey=error").Parse(textLayout)
+	if err == nil {
+		_, err = text.Parse(textBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	htmlLayout, err := t.read("layout.html", locale)
+	if err != nil {
+		return nil, err
+	}
+	htmlBody, err := t.read(name+".html", locale)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New("layout").Option("missingkey=error").Parse(htmlLayout)
+	if err == nil {
+		_, err = html.Parse(htmlBody)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var subject, textOut, htmlOut bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", vars); err != nil {
+		return nil, err
+	}
+	if err := text.Execute(&textOut, vars); err != nil {
+		return nil, err
+	}
+	if err := html.Execute(&htmlOut, vars); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    textOut.String(),
+		HTML:    htmlOut.String(),
+	}, nil
+}
+
+// read returns file from the most specific locale that has it: pt-BR, then pt, then the default
+func (t *Templates) read(file, locale string) (string, error) {
+	for _, l := range localeChain(locale) {
+		b, err := fs.ReadFile(t.files, path.Join(l, file))
+		if err == nil {
+			return string(b), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", errors.New("mail template " + file + " not found")
+}
+
+func localeChain(locale string) []string {
+	var chain []string
+	locale = strings.ReplaceAll(locale, "_", "-")
+	// only plain language tags, so a locale can't name another directory
+	if locale != "" && !strings.ContainsAny(locale, "./\\") {
+		chain = append(chain, locale)
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			chain = append(chain, lang)
+		}
+	}
+	return append(chain, DefaultLocale)
+}
+
+// overlayFS opens each file from the first filesystem that has it
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for _, f := range o {
+		file, err := f.Open(name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return file, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
@@ -0,0 +1,265 @@
+package mail
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/config"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"go.uber.org/zap"
+)
+
+// Transport delivers messages
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// Transport kinds, chosen with MAIL_TRANSPORT
+const (
+	TransportSMTP = "smtp"
+	TransportFile = "file"
+	TransportLog  = "log"
+)
+
+// SMTP connection security, chosen with SMTP_TLS
+const (
+	// TLSStartTLS upgrades a plain connection and refuses servers without STARTTLS
+	TLSStartTLS = "starttls"
+	// TLSImplicit speaks TLS from the start, usually on port 465
+	TLSImplicit = "tls"
+	// TLSNone sends in the clear, for local test servers
+	TLSNone = "none"
+)
+
+// NewTransport creates the transport selected in cfg
+func NewTransport(cfg *config.Config) (Transport, error) {
+	switch cfg.MailTransport {
+	case TransportSMTP:
+		port, err := strconv.Atoi(cfg.SMTPPort)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP port %q", cfg.SMTPPort)
+		}
+		return NewSMTPTransport(SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     port,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPass,
+			TLS:      cfg.SMTPTLS,
+			PoolSize: cfg.SMTPPoolSize,
+		})
+	case TransportFile:
+		return NewFileTransport(cfg.MailDir)
+	case TransportLog:
+		return LogTransport{}, nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q", cfg.MailTransport)
+}
+
+// SMTPConfig configures an SMTPTransport
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      string
+	// PoolSize is how many idle connections are kept open for reuse
+	PoolSize int
+	// Timeout bounds each message, from taking a connection to the server
+	// accepting it, so a stalled server can't hold a job worker. Defaults to a minute.
+	Timeout time.Duration
+}
+
+// SMTPTransport sends through an SMTP server, reusing connections between messages
+type SMTPTransport struct {
+	cfg  SMTPConfig
+	idle chan *smtpConn
+}
+
+// smtpConn keeps the network connection of a client to set its deadlines
+type smtpConn struct {
+	*smtp.Client
+	conn net.Conn
+}
+
+// NewSMTPTransport creates an SMTP transport. Connections are opened on first use.
+func NewSMTPTransport(cfg SMTPConfig) (*SMTPTransport, error) {
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = TLSStartTLS
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", cfg.TLS)
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 2
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = time.Minute
+	}
+	return &SMTPTransport{cfg: cfg, idle: make(chan *smtpConn, cfg.PoolSize)}, nil
+}
+
+func (t *SMTPTransport) Send(msg *Message) error {
+	c, err := t.conn()
+	if err != nil {
+		return err
+	}
+
+	if err := send(c.Client, msg); err != nil {
+		// the connection is in an unknown state, so it isn't reused
+		c.Close()
+		return err
+	}
+
+	select {
+	case t.idle <- c:
+	default:
+		c.Quit()
+	}
+	return nil
+}
+
+// Close closes the idle connections
+func (t *SMTPTransport) Close() error {
+	for {
+		select {
+		case c := <-t.idle:
+			c.conn.SetDeadline(time.Now().Add(t.cfg.Timeout))
+			c.Quit()
+		default:
+			return nil
+		}
+	}
+}
+
+// conn returns an idle connection that still works, or a new one, with its
+// deadline set for one message
+func (t *SMTPTransport) conn() (*smtpConn, error) {
+	for {
+		select {
+		case c := <-t.idle:
+			c.conn.SetDeadline(time.Now().Add(t.cfg.Timeout))
+			// servers drop connections that sat idle too long
+			if err := c.Noop(); err == nil {
+				return c, nil
+			}
+			c.Close()
+		default:
+			return t.dial()
+		}
+	}
+}
+
+func (t *SMTPTransport) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(t.cfg.Host, strconv.Itoa(t.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: t.cfg.Host}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+
+	var conn net.Conn
+	var err error
+	if t.cfg.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// also covers the greeting, STARTTLS and authentication
+	conn.SetDeadline(time.Now().Add(t.cfg.Timeout))
+
+	c, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if t.cfg.TLS == TLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if t.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return &smtpConn{Client: c, conn: conn}, nil
+}
+
+func send(c *smtp.Client, msg *Message) error {
+	if err := c.Mail(msg.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// FileTransport saves each message as a .eml file in a directory instead of sending it
+type FileTransport struct {
+	dir string
+}
+
+// NewFileTransport creates a file transport writing to dir, creating it if needed
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileTransport{dir: dir}, nil
+}
+
+func (t *FileTransport) Send(msg *Message) error {
+	// names sort in the order messages were sent
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+	f, err := os.Create(filepath.Join(t.dir, name))
+	if err != nil {
+		return err
+	}
+	if _, err := msg.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LogTransport only logs who a message is for and its subject. The body is
+// left out since it may hold reset or verification links.
+type LogTransport struct{}
+
+func (LogTransport) Send(msg *Message) error {
+	log.Logger.Info("email",
+		zap.String("from", msg.From),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+	)
+	return nil
+}
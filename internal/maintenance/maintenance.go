@@ -0,0 +1,122 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/forks"
+	"github.com/GordenArcher/mini-github/internal/jobs"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"gorm.io/gorm"
+)
+
+// Job types
+const (
+	// JobRepository maintains one repository
+	JobRepository = "maintenance.repository"
+	// JobSweep queues maintenance for every repository
+	JobSweep = "maintenance.sweep"
+	// JobPurge removes repositories deleted longer ago than the restore window
+	JobPurge = "maintenance.purge"
+)
+
+const (
+	// SweepInterval is how often every repository is queued for maintenance
+	SweepInterval = 24 * time.Hour
+	// PurgeInterval is how often deleted repositories are checked for purging
+	PurgeInterval = time.Hour
+)
+
+type repositoryJob struct {
+	RepositoryID uint `json:"repository_id"`
+}
+
+// Run packs loose objects and refs and prunes unreachable objects in the bare
+// repository at path. Git skips the work when the repository is already tidy.
+func Run(ctx context.Context, path string) error {
+	cmd := exec.CommandContext(ctx, "git", "gc", "--auto", "--quiet")
+	cmd.Dir = path
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git gc: %w: %s", err, out)
+	}
+	return nil
+}
+
+// Enqueue queues maintenance for one repository
+func Enqueue(ctx context.Context, repoID uint) error {
+	return jobs.Enqueue(ctx, jobs.QueueMaintenance, JobRepository, repositoryJob{RepositoryID: repoID})
+}
+
+// HandleRepository is the job handler that maintains one repository
+func HandleRepository(dbConn *db.DB) jobs.Handler {
+	return func(ctx context.Context, j *jobs.Job) error {
+		var job repositoryJob
+		if err := j.Decode(&job); err != nil {
+			return err
+		}
+
+		var repo db.Repository
+		if err := dbConn.First(&repo, job.RepositoryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		// repositories predating storage locations may have nothing on disk,
+		// and an empty path would mean the storage root
+		if repo.Path == "" {
+			return nil
+		}
+		return Run(ctx, storage.Path(repo.Path))
+	}
+}
+
+// HandleSweep is the job handler that queues maintenance for every repository
+func HandleSweep(dbConn *db.DB) jobs.Handler {
+	return func(ctx context.Context, j *jobs.Job) error {
+		var ids []uint
+		if err := dbConn.Model(&db.Repository{}).Order("id").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := Enqueue(ctx, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// HandlePurge is the job handler that permanently removes repositories
+// deleted more than window ago, Git data included
+func HandlePurge(dbConn *db.DB, window time.Duration) jobs.Handler {
+	return func(ctx context.Context, j *jobs.Job) error {
+		repos, err := dbConn.DeletedRepositories(time.Now().Add(-window))
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, repo := range repos {
+			// forks borrowing its objects get their own copies first
+			if err := forks.Detach(ctx, dbConn, &repo); err != nil {
+				errs = append(errs, fmt.Errorf("repository %d: %w", repo.ID, err))
+				continue
+			}
+			if repo.Path != "" {
+				if err := storage.Delete(repo.Path); err != nil {
+					errs = append(errs, fmt.Errorf("repository %d: %w", repo.ID, err))
+					continue
+				}
+			}
+			if err := dbConn.DeleteRepository(&repo); err != nil {
+				errs = append(errs, fmt.Errorf("repository %d: %w", repo.ID, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
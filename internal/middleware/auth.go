@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/GordenArcher/mini-github/internal/db"
 	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/GordenArcher/mini-github/internal/helper/utils/tokens"
 	"github.com/GordenArcher/mini-github/internal/redis"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,11 +16,18 @@ import (
 
 var jwtAccessSecret string
 
+var tokenDB *db.DB
+
 // SetJWTSecret initializes the JWT secret
 func SetJWTSecret(secret string) {
 	jwtAccessSecret = secret
 }
 
+// SetDB initializes the database used to look up personal access tokens
+func SetDB(dbConn *db.DB) {
+	tokenDB = dbConn
+}
+
 // AuthMiddleware is a shorthand wrapper for JWTAuthMiddleware using the stored secret
 func AuthMiddleware() gin.HandlerFunc {
 	if jwtAccessSecret == "" {
@@ -26,54 +37,129 @@ func AuthMiddleware() gin.HandlerFunc {
 	return JWTAuthMiddleware(jwtAccessSecret)
 }
 
-// JWTAuthMiddleware handles JWT validation
+// JWTAuthMiddleware handles JWT validation. Personal access tokens are
+// accepted in place of a JWT once SetDB has been called.
 func JWTAuthMiddleware(accessSecret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
 		if auth == "" {
 			responses.JSONError(c, http.StatusUnauthorized, "authorization header required")
+			c.Abort()
 			return
 		}
 		parts := strings.SplitN(auth, " ", 2)
 		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 			responses.JSONError(c, http.StatusUnauthorized, "authorization header format must be Bearer {token}")
+			c.Abort()
 			return
 		}
-		tokenString := parts[1]
-
-		// parse token
-		token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrTokenMalformed
-			}
-			return []byte(accessSecret), nil
-		})
-		if err != nil || !token.Valid {
-			responses.JSONError(c, http.StatusUnauthorized, "invalid token")
-			return
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			responses.JSONError(c, http.StatusUnauthorized, "invalid token claims")
+
+		userID, scopes, err := authenticateToken(parts[1], accessSecret)
+		if err != nil {
+			responses.JSONError(c, http.StatusUnauthorized, err.Error())
+			c.Abort()
 			return
 		}
+		setAuthenticatedUser(c, userID, scopes)
+		c.Next()
+	}
+}
 
-		// check jti blacklist
-		if jti, ok := claims["jti"].(string); ok {
-			black, err := redis.IsAccessTokenBlacklisted(jti)
-			if err == nil && black {
-				responses.JSONError(c, http.StatusUnauthorized, "token revoked")
-				return
-			}
+// setAuthenticatedUser stores the caller on the context. scopes is nil for
+// JWT sessions, which are not restricted.
+func setAuthenticatedUser(c *gin.Context, userID uint, scopes []string) {
+	c.Set("user_id", userID)
+	if scopes != nil {
+		c.Set("token_scopes", scopes)
+	}
+}
+
+// authenticateToken accepts either a JWT access token or a personal access token
+func authenticateToken(tokenString, accessSecret string) (uint, []string, error) {
+	var userID uint
+	var scopes []string
+	var err error
+	if tokens.IsPersonalAccessToken(tokenString) {
+		userID, scopes, err = parsePersonalAccessToken(tokenString)
+	} else {
+		userID, err = parseAccessToken(tokenString, accessSecret)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := checkUserActive(userID); err != nil {
+		return 0, nil, err
+	}
+	return userID, scopes, nil
+}
+
+// checkUserActive refuses tokens of users an administrator has disabled
+func checkUserActive(userID uint) error {
+	if tokenDB == nil {
+		return nil
+	}
+	disabled, err := tokenDB.UserDisabled(userID)
+	if err != nil {
+		return errors.New("invalid token")
+	}
+	if disabled {
+		return errors.New("account disabled")
+	}
+	return nil
+}
+
+// parsePersonalAccessToken looks up a personal access token and records its use
+func parsePersonalAccessToken(tokenString string) (uint, []string, error) {
+	if tokenDB == nil {
+		return 0, nil, errors.New("invalid token")
+	}
+
+	var pat db.PersonalAccessToken
+	if err := tokenDB.Where("token_hash = ?", tokens.Hash(tokenString)).First(&pat).Error; err != nil {
+		return 0, nil, errors.New("invalid token")
+	}
+	if pat.ExpiresAt != nil && pat.ExpiresAt.Before(time.Now()) {
+		return 0, nil, errors.New("token expired")
+	}
+
+	tokenDB.Model(&pat).UpdateColumn("last_used_at", time.Now())
+
+	// an empty scope list still has to be distinguishable from a JWT session
+	scopes := pat.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return pat.UserID, scopes, nil
+}
+
+// parseAccessToken validates a signed access token and returns its subject
+func parseAccessToken(tokenString, accessSecret string) (uint, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrTokenMalformed
 		}
+		return []byte(accessSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, errors.New("invalid token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, errors.New("invalid token claims")
+	}
 
-		// extract sub
-		sub, ok := claims["sub"].(float64)
-		if !ok {
-			responses.JSONError(c, http.StatusUnauthorized, "invalid token subject")
-			return
+	// check jti blacklist
+	if jti, ok := claims["jti"].(string); ok {
+		black, err := redis.IsAccessTokenBlacklisted(jti)
+		if err == nil && black {
+			return 0, errors.New("token revoked")
 		}
-		c.Set("user_id", uint(sub))
-		c.Next()
 	}
+
+	// extract sub
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, errors.New("invalid token subject")
+	}
+	return uint(sub), nil
 }
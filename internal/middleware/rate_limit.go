@@ -11,13 +11,14 @@ import (
 	limiterredis "github.com/ulule/limiter/v3/drivers/store/redis"
 )
 
-func RateLimitMiddleware() gin.HandlerFunc {
+// RateLimitMiddleware allows each client IP limit requests per period
+func RateLimitMiddleware(limit int64, period time.Duration) gin.HandlerFunc {
 	store, err := limiterredis.NewStore(redis.Client)
 	if err != nil {
 		panic(err)
 	}
 
-	rate := limiterlib.Rate{Period: time.Minute, Limit: 10}
+	rate := limiterlib.Rate{Period: period, Limit: limit}
 	limiter := limiterlib.New(store, rate)
 
 	return func(c *gin.Context) {
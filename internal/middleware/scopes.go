@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/GordenArcher/mini-github/internal/helper/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// Scopes that can be granted to personal access tokens
+const (
+	ScopeRepoRead  = "repo:read"
+	ScopeRepoWrite = "repo:write"
+	ScopeUser      = "user"
+)
+
+// IsValidScope reports whether scope can be granted to a token
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeRepoRead, ScopeRepoWrite, ScopeUser:
+		return true
+	}
+	return false
+}
+
+// HasScope reports whether the request's credentials grant scope.
+// JWT sessions carry no scopes and may do anything the user can.
+func HasScope(c *gin.Context, scope string) bool {
+	v, ok := c.Get("token_scopes")
+	if !ok {
+		return true
+	}
+	scopes := v.([]string)
+
+	// write access to repositories implies read access
+	if scope == ScopeRepoRead && slices.Contains(scopes, ScopeRepoWrite) {
+		return true
+	}
+	return slices.Contains(scopes, scope)
+}
+
+// RequireScope rejects requests made with a token that lacks scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasScope(c, scope) {
+			responses.JSONError(c, http.StatusForbidden, "token is missing the "+scope+" scope")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+)
+
+const usage = "usage: migrate up | down [n] | status | to <version>"
+
+// Command runs the migrate command line, given the arguments after "migrate",
+// and reports what it did to w
+func Command(dbConn *db.DB, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	var ran []Migration
+	var err error
+	switch args[0] {
+	case "up":
+		ran, err = Up(dbConn)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return errors.New(usage)
+			}
+		}
+		ran, err = Down(dbConn, n)
+	case "to":
+		if len(args) < 2 {
+			return errors.New(usage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return errors.New(usage)
+		}
+		ran, err = To(dbConn, version)
+	case "status":
+		return printStatus(dbConn, w)
+	default:
+		return errors.New(usage)
+	}
+
+	for _, m := range ran {
+		fmt.Fprintf(w, "%04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	version, err := Current(dbConn)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "schema at version %d\n", version)
+	return nil
+}
+
+func printStatus(dbConn *db.DB, w io.Writer) error {
+	statuses, err := StatusOf(dbConn)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%04d_%-40s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
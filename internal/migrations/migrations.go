@@ -0,0 +1,257 @@
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"gorm.io/gorm"
+)
+
+// Migrations live in sql/ as NNNN_name.up.sql and NNNN_name.down.sql.
+// Versions must increase by one; a released migration is never edited,
+// a change to it is a new migration.
+//
+//go:embed sql/*.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// lockID keeps two servers from migrating at once, see pg_advisory_xact_lock
+const lockID = 7_361_208_114
+
+// ErrPending is returned by Check when the schema is behind the code
+var ErrPending = errors.New("database schema is not up to date")
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// schemaMigration records an applied migration
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// Status is a migration and when it was applied, if it was
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// All returns every migration, oldest first
+func All() ([]Migration, error) {
+	return parse(files)
+}
+
+func parse(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, path := range entries {
+		base := path[len("sql/"):]
+		m := fileName.FindStringSubmatch(base)
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_name.up.sql", base)
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	all := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		all = append(all, *m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	for i, m := range all {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", m.Version)
+		}
+	}
+	return all, nil
+}
+
+// Latest is the version the code expects the schema to be at
+func Latest() (int, error) {
+	all, err := All()
+	if err != nil {
+		return 0, err
+	}
+	return len(all), nil
+}
+
+// Current returns the version the schema is at, 0 for an empty database
+func Current(dbConn *db.DB) (int, error) {
+	if err := ensureTable(dbConn.DB); err != nil {
+		return 0, err
+	}
+	return current(dbConn.DB)
+}
+
+func current(tx *gorm.DB) (int, error) {
+	var version int
+	err := tx.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Check returns ErrPending unless every migration has been applied
+func Check(dbConn *db.DB) error {
+	latest, err := Latest()
+	if err != nil {
+		return err
+	}
+	version, err := Current(dbConn)
+	if err != nil {
+		return err
+	}
+	if version < latest {
+		return fmt.Errorf("%w: at version %d of %d, run the migrate up command", ErrPending, version, latest)
+	}
+	if version > latest {
+		return fmt.Errorf("database schema version %d is newer than this build knows (%d)", version, latest)
+	}
+	return nil
+}
+
+// StatusOf lists every migration with when it was applied
+func StatusOf(dbConn *db.DB) ([]Status, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(dbConn.DB); err != nil {
+		return nil, err
+	}
+	var applied []schemaMigration
+	if err := dbConn.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	at := map[int]time.Time{}
+	for _, a := range applied {
+		at[a.Version] = a.AppliedAt
+	}
+
+	statuses := make([]Status, len(all))
+	for i, m := range all {
+		statuses[i] = Status{Migration: m}
+		if t, ok := at[m.Version]; ok {
+			statuses[i].AppliedAt = &t
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration and returns those it applied
+func Up(dbConn *db.DB) ([]Migration, error) {
+	latest, err := Latest()
+	if err != nil {
+		return nil, err
+	}
+	return To(dbConn, latest)
+}
+
+// Down rolls back the n most recent migrations and returns them
+func Down(dbConn *db.DB, n int) ([]Migration, error) {
+	version, err := Current(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	return To(dbConn, max(version-n, 0))
+}
+
+// To migrates up or down until the schema is at target, one transaction per
+// migration, and returns the migrations it ran
+func To(dbConn *db.DB, target int) ([]Migration, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	if target < 0 || target > len(all) {
+		return nil, fmt.Errorf("no migration %d, the latest is %d", target, len(all))
+	}
+	if err := ensureTable(dbConn.DB); err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for {
+		var step *Migration
+		err := dbConn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+				return err
+			}
+			// another server may have moved the schema while this one waited for the lock
+			version, err := current(tx)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case version < target:
+				m := all[version]
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+				}
+				step = &m
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			case version > target:
+				m := all[version-1]
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+				}
+				step = &m
+				return tx.Delete(&schemaMigration{}, m.Version).Error
+			}
+			return nil
+		})
+		if err != nil {
+			return ran, err
+		}
+		if step == nil {
+			return ran, nil
+		}
+		ran = append(ran, *step)
+	}
+}
+
+func ensureTable(tx *gorm.DB) error {
+	return tx.Exec(`CREATE TABLE IF NOT EXISTS "schema_migrations" (
+		"version" bigint PRIMARY KEY,
+		"name" text NOT NULL,
+		"applied_at" timestamptz NOT NULL
+	)`).Error
+}
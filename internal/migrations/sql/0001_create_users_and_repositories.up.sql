@@ -0,0 +1,31 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the old
+-- AutoMigrate startup adopt versioned migrations without changes.
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "username" text NOT NULL,
+    "email" text NOT NULL,
+    "password" text NOT NULL,
+    "display_name" text,
+    "avatar_url" text,
+    "bio" text,
+    "is_verified" boolean DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+
+CREATE TABLE IF NOT EXISTS "repositories" (
+    "id" bigserial,
+    "name" text NOT NULL,
+    "description" text,
+    "visibility" text DEFAULT 'private',
+    "path" text NOT NULL,
+    "default_branch" text NOT NULL DEFAULT 'main',
+    "owner_id" bigint,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_repositories_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id")
+);
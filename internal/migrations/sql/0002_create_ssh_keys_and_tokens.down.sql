@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "personal_access_tokens";
+DROP TABLE IF EXISTS "ssh_keys";
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS "ssh_keys" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "title" text NOT NULL,
+    "public_key" text NOT NULL,
+    "fingerprint" text NOT NULL,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_ssh_keys_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_ssh_keys_fingerprint" ON "ssh_keys" ("fingerprint");
+CREATE INDEX IF NOT EXISTS "idx_ssh_keys_user_id" ON "ssh_keys" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "personal_access_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "token_prefix" text NOT NULL,
+    "scopes" text NOT NULL,
+    "expires_at" timestamptz,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_personal_access_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_personal_access_tokens_token_hash" ON "personal_access_tokens" ("token_hash");
+CREATE INDEX IF NOT EXISTS "idx_personal_access_tokens_user_id" ON "personal_access_tokens" ("user_id");
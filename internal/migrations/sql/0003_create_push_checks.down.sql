@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "secret_alerts";
+DROP TABLE IF EXISTS "repository_policies";
+DROP TABLE IF EXISTS "branch_protections";
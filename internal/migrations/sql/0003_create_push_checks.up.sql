@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS "branch_protections" (
+    "id" bigserial,
+    "repository_id" bigint NOT NULL,
+    "pattern" text NOT NULL,
+    "block_force_pushes" boolean,
+    "block_deletions" boolean,
+    "require_linear_history" boolean,
+    "restrict_pushes" boolean,
+    "allowed_pusher_ids" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_branch_protection_pattern" ON "branch_protections" ("repository_id", "pattern");
+
+CREATE TABLE IF NOT EXISTS "repository_policies" (
+    "id" bigserial,
+    "repository_id" bigint NOT NULL,
+    "max_file_size" bigint,
+    "forbidden_paths" text,
+    "commit_message_pattern" text,
+    "author_email_domains" text,
+    "secret_scanning" text,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_repository_policies_repository_id" ON "repository_policies" ("repository_id");
+
+CREATE TABLE IF NOT EXISTS "secret_alerts" (
+    "id" bigserial,
+    "repository_id" bigint NOT NULL,
+    "commit_sha" text NOT NULL,
+    "path" text NOT NULL,
+    "line" bigint NOT NULL,
+    "rule" text NOT NULL,
+    "ref" text,
+    "state" text NOT NULL DEFAULT 'open',
+    "dismissed_reason" text,
+    "dismissed_by_id" bigint,
+    "dismissed_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_secret_alert" ON "secret_alerts" ("repository_id", "commit_sha", "path", "line", "rule");
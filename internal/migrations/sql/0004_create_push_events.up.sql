@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "push_events" (
+    "id" bigserial,
+    "repository_id" bigint NOT NULL,
+    "pusher_id" bigint NOT NULL,
+    "ref" text NOT NULL,
+    "before" text NOT NULL,
+    "after" text NOT NULL,
+    "commit_count" bigint,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_push_events_repository" FOREIGN KEY ("repository_id") REFERENCES "repositories"("id"),
+    CONSTRAINT "fk_push_events_pusher" FOREIGN KEY ("pusher_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_push_events_created_at" ON "push_events" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_push_events_pusher_id" ON "push_events" ("pusher_id");
+CREATE INDEX IF NOT EXISTS "idx_push_events_repository_id" ON "push_events" ("repository_id");
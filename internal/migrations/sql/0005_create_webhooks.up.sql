@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS "webhooks" (
+    "id" bigserial,
+    "owner_id" bigint NOT NULL,
+    "repository_id" bigint,
+    "url" text NOT NULL,
+    "secret" text,
+    "events" text NOT NULL,
+    "active" boolean NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_webhooks_owner_id" ON "webhooks" ("owner_id");
+CREATE INDEX IF NOT EXISTS "idx_webhooks_repository_id" ON "webhooks" ("repository_id");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" (
+    "id" bigserial,
+    "webhook_id" bigint NOT NULL,
+    "guid" text NOT NULL,
+    "event" text NOT NULL,
+    "redelivery_of_id" bigint,
+    "status" text NOT NULL,
+    "attempts" bigint,
+    "next_attempt_at" timestamptz,
+    "request_headers" text,
+    "request_body" text,
+    "response_status" bigint,
+    "response_headers" text,
+    "response_body" text,
+    "error" text,
+    "duration_ms" bigint,
+    "delivered_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_webhook_deliveries_guid" ON "webhook_deliveries" ("guid");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_webhook_id" ON "webhook_deliveries" ("webhook_id");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_next_attempt_at" ON "webhook_deliveries" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_status" ON "webhook_deliveries" ("status");
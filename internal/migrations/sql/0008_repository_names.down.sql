@@ -0,0 +1,3 @@
+-- renamed duplicates keep their new names
+DROP TABLE IF EXISTS "repository_redirects";
+DROP INDEX IF EXISTS "idx_repositories_owner_name";
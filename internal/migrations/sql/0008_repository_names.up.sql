@@ -0,0 +1,21 @@
+-- Repository names are unique per owner regardless of case. Names that
+-- only differ in case get the repository ID appended, oldest keeps its name.
+UPDATE "repositories" AS r SET "name" = r."name" || '-' || r."id"
+WHERE EXISTS (
+    SELECT 1 FROM "repositories" AS o
+    WHERE o."owner_id" = r."owner_id" AND lower(o."name") = lower(r."name") AND o."id" < r."id"
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_repositories_owner_name" ON "repositories" ("owner_id", lower("name"));
+
+-- Old owners and names of renamed or transferred repositories
+CREATE TABLE IF NOT EXISTS "repository_redirects" (
+    "id" bigserial,
+    "owner_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "repository_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_repository_redirects_repository" FOREIGN KEY ("repository_id") REFERENCES "repositories"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_repository_redirects_owner_name" ON "repository_redirects" ("owner_id", lower("name"));
+CREATE INDEX IF NOT EXISTS "idx_repository_redirects_repository_id" ON "repository_redirects" ("repository_id");
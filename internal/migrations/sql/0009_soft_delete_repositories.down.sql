@@ -0,0 +1,7 @@
+-- Deleted repositories that are not purged yet come back. This fails while
+-- one shares its name with another repository of the same owner.
+DROP INDEX IF EXISTS "idx_repositories_owner_name";
+CREATE UNIQUE INDEX "idx_repositories_owner_name" ON "repositories" ("owner_id", lower("name"));
+
+DROP INDEX IF EXISTS "idx_repositories_deleted_at";
+ALTER TABLE "repositories" DROP COLUMN IF EXISTS "deleted_at";
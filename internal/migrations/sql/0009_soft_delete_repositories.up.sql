@@ -0,0 +1,6 @@
+ALTER TABLE "repositories" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_repositories_deleted_at" ON "repositories" ("deleted_at");
+
+-- names of deleted repositories can be taken again
+DROP INDEX IF EXISTS "idx_repositories_owner_name";
+CREATE UNIQUE INDEX "idx_repositories_owner_name" ON "repositories" ("owner_id", lower("name")) WHERE "deleted_at" IS NULL;
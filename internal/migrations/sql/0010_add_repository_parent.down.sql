@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "idx_repositories_parent_id";
+ALTER TABLE "repositories" DROP CONSTRAINT IF EXISTS "fk_repositories_parent";
+ALTER TABLE "repositories" DROP COLUMN IF EXISTS "parent_id";
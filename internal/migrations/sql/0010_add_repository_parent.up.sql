@@ -0,0 +1,4 @@
+-- Forks record the repository they were forked from
+ALTER TABLE "repositories" ADD COLUMN IF NOT EXISTS "parent_id" bigint;
+ALTER TABLE "repositories" ADD CONSTRAINT "fk_repositories_parent" FOREIGN KEY ("parent_id") REFERENCES "repositories"("id");
+CREATE INDEX IF NOT EXISTS "idx_repositories_parent_id" ON "repositories" ("parent_id");
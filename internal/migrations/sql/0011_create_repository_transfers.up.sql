@@ -0,0 +1,14 @@
+-- Transfers offered by a repository's owner, waiting for the recipient to accept
+CREATE TABLE IF NOT EXISTS "repository_transfers" (
+    "id" bigserial,
+    "repository_id" bigint NOT NULL,
+    "from_id" bigint NOT NULL,
+    "to_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_repository_transfers_repository" FOREIGN KEY ("repository_id") REFERENCES "repositories"("id"),
+    CONSTRAINT "fk_repository_transfers_from" FOREIGN KEY ("from_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_repository_transfers_to" FOREIGN KEY ("to_id") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_repository_transfers_repository_id" ON "repository_transfers" ("repository_id");
+CREATE INDEX IF NOT EXISTS "idx_repository_transfers_to_id" ON "repository_transfers" ("to_id");
@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// MaxFileSize rejects commits adding or modifying a file larger than the limit, in bytes
+type MaxFileSize int64
+
+func (limit MaxFileSize) Check(c *Commit) *Violation {
+	for _, f := range c.Files {
+		if f.Size > int64(limit) {
+			return &Violation{
+				Commit: c.Hash,
+				File:   f.Path,
+				Reason: fmt.Sprintf("file is %s, larger than the %s limit", formatSize(f.Size), formatSize(int64(limit))),
+			}
+		}
+	}
+	return nil
+}
+
+// ForbiddenPaths rejects commits adding or modifying files matching one of
+// the path.Match patterns. Patterns without a slash, such as .env or *.pem,
+// also match the file name in any directory.
+type ForbiddenPaths []string
+
+func (patterns ForbiddenPaths) Check(c *Commit) *Violation {
+	for _, f := range c.Files {
+		for _, pattern := range patterns {
+			if matchPath(pattern, f.Path) {
+				return &Violation{Commit: c.Hash, File: f.Path, Reason: "path matches forbidden pattern " + pattern}
+			}
+		}
+	}
+	return nil
+}
+
+func matchPath(pattern, p string) bool {
+	if ok, _ := path.Match(pattern, p); ok {
+		return true
+	}
+	if strings.Contains(pattern, "/") {
+		return false
+	}
+	ok, _ := path.Match(pattern, path.Base(p))
+	return ok
+}
+
+// CommitMessage rejects commits whose message doesn't match a regular expression
+type CommitMessage struct {
+	pattern *regexp.Regexp
+}
+
+// NewCommitMessage compiles the regular expression every commit message must match
+func NewCommitMessage(pattern string) (*CommitMessage, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &CommitMessage{pattern: re}, nil
+}
+
+func (p *CommitMessage) Check(c *Commit) *Violation {
+	if p.pattern.MatchString(c.Message) {
+		return nil
+	}
+	return &Violation{Commit: c.Hash, Reason: "commit message does not match " + p.pattern.String()}
+}
+
+// AuthorEmailDomains rejects commits whose author email is outside the
+// listed domains. Subdomains are not included.
+type AuthorEmailDomains []string
+
+func (domains AuthorEmailDomains) Check(c *Commit) *Violation {
+	_, domain, _ := strings.Cut(c.Author.Email, "@")
+	for _, d := range domains {
+		if strings.EqualFold(domain, d) {
+			return nil
+		}
+	}
+	return &Violation{
+		Commit: c.Hash,
+		Reason: fmt.Sprintf("author email %s is not in an allowed domain (%s)", c.Author.Email, strings.Join(domains, ", ")),
+	}
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
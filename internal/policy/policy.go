@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Commit is a pushed commit together with the files it adds or modifies
+// compared to its first parent
+type Commit struct {
+	*object.Commit
+	Files []File
+}
+
+// File is a file a commit adds or modifies
+type File struct {
+	Path string
+	Size int64
+}
+
+// Policy checks the commits of a push. Check returns nil when c is acceptable.
+type Policy interface {
+	Check(c *Commit) *Violation
+}
+
+// Violation is a commit breaking a policy. File is empty for policies about
+// the commit itself, such as its message.
+type Violation struct {
+	Commit plumbing.Hash
+	File   string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	if v.File == "" {
+		return fmt.Sprintf("commit %s: %s", v.Commit.String()[:7], v.Reason)
+	}
+	return fmt.Sprintf("commit %s: %s: %s", v.Commit.String()[:7], v.File, v.Reason)
+}
+
+// FromConfig builds the policies a repository has switched on
+func FromConfig(cfg *db.RepositoryPolicy) ([]Policy, error) {
+	var policies []Policy
+	if cfg.MaxFileSize > 0 {
+		policies = append(policies, MaxFileSize(cfg.MaxFileSize))
+	}
+	if len(cfg.ForbiddenPaths) > 0 {
+		policies = append(policies, ForbiddenPaths(cfg.ForbiddenPaths))
+	}
+	if cfg.CommitMessagePattern != "" {
+		p, err := NewCommitMessage(cfg.CommitMessagePattern)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, p)
+	}
+	if len(cfg.AuthorEmailDomains) > 0 {
+		policies = append(policies, AuthorEmailDomains(cfg.AuthorEmailDomains))
+	}
+	return policies, nil
+}
+
+// Hook checks every commit a push brings in against the repository's policies
+func Hook(dbConn *db.DB) gitserver.PreReceiveHook {
+	return gitserver.PreReceiveFunc(func(ctx context.Context, p *gitserver.Push) error {
+		var cfg db.RepositoryPolicy
+		res := dbConn.Where("repository_id = ?", p.Repo.ID).Limit(1).Find(&cfg)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		policies, err := FromConfig(&cfg)
+		if err != nil || len(policies) == 0 {
+			return err
+		}
+
+		// a commit pushed to several refs is reported against each of them
+		violations := map[plumbing.Hash]*Violation{}
+		var rejected []error
+		for _, u := range p.Updates {
+			commits, err := p.NewCommits(u)
+			if err != nil {
+				return err
+			}
+			for _, c := range commits {
+				v, seen := violations[c.Hash]
+				if !seen {
+					if v, err = check(c, policies); err != nil {
+						return err
+					}
+					violations[c.Hash] = v
+				}
+				if v != nil {
+					rejected = append(rejected, &gitserver.Rejection{Ref: u.Name, Reason: v.Error()})
+					break
+				}
+			}
+		}
+		return errors.Join(rejected...)
+	})
+}
+
+// check runs policies against c and returns the first violation
+func check(c *object.Commit, policies []Policy) (*Violation, error) {
+	commit, err := NewCommit(c)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range policies {
+		if v := p.Check(commit); v != nil {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// NewCommit lists the files c adds or modifies
+func NewCommit(c *object.Commit) (*Commit, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return nil, err
+	}
+
+	commit := &Commit{Commit: c}
+	for _, ch := range changes {
+		// deletions have no destination, submodules no blob
+		if ch.To.Name == "" || ch.To.TreeEntry.Mode == filemode.Submodule {
+			continue
+		}
+		_, to, err := ch.Files()
+		if err != nil {
+			return nil, err
+		}
+		commit.Files = append(commit.Files, File{Path: ch.To.Name, Size: to.Size})
+	}
+	return commit, nil
+}
@@ -0,0 +1,92 @@
+package protection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+)
+
+// Hook enforces branch protection rules on every push
+func Hook(dbConn *db.DB) gitserver.PreReceiveHook {
+	return gitserver.PreReceiveFunc(func(ctx context.Context, p *gitserver.Push) error {
+		var rejected []error
+		for _, u := range p.Updates {
+			if !u.Name.IsBranch() {
+				continue
+			}
+			rules, err := dbConn.BranchProtections(p.Repo.ID, u.Name.Short())
+			if err != nil {
+				return err
+			}
+			if len(rules) == 0 {
+				continue
+			}
+
+			reason, err := check(p, u, rules)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				rejected = append(rejected, &gitserver.Rejection{Ref: u.Name, Reason: reason})
+			}
+		}
+		return errors.Join(rejected...)
+	})
+}
+
+// check returns why u breaks one of rules, or "" if it breaks none
+func check(p *gitserver.Push, u gitserver.RefUpdate, rules []db.BranchProtection) (string, error) {
+	branch := u.Name.Short()
+
+	for _, r := range rules {
+		if !r.AllowsPusher(p.PusherID) {
+			return fmt.Sprintf("you are not allowed to push to protected branch %s", branch), nil
+		}
+	}
+
+	if u.IsDelete() {
+		for _, r := range rules {
+			if r.BlockDeletions {
+				return fmt.Sprintf("protected branch %s cannot be deleted", branch), nil
+			}
+		}
+		return "", nil
+	}
+
+	if !u.IsCreate() {
+		for _, r := range rules {
+			if !r.BlockForcePushes {
+				continue
+			}
+			ff, err := p.IsFastForward(u)
+			if err != nil {
+				return "", err
+			}
+			if !ff {
+				return fmt.Sprintf("force pushes to protected branch %s are not allowed", branch), nil
+			}
+			break
+		}
+	}
+
+	for _, r := range rules {
+		if !r.RequireLinearHistory {
+			continue
+		}
+		commits, err := p.NewCommits(u)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range commits {
+			if c.NumParents() > 1 {
+				return fmt.Sprintf("protected branch %s requires linear history, %s is a merge commit", branch, c.Hash.String()[:7]), nil
+			}
+		}
+		break
+	}
+
+	return "", nil
+}
@@ -1,8 +1,6 @@
 package routes
 
 import (
-	"time"
-
 	"github.com/GordenArcher/mini-github/internal/config"
 	"github.com/GordenArcher/mini-github/internal/db"
 	"github.com/GordenArcher/mini-github/internal/handlers"
@@ -18,8 +16,8 @@ func RegisterAuthRoutes(r *gin.RouterGroup, dbConn *db.DB, mailer *mail.Mailer,
 		auth.POST("/register", handlers.RegisterHandler(dbConn, mailer))
 		auth.GET("/verify", handlers.VerifyEmailHandler(dbConn))
 		auth.POST("/resend-verification", handlers.ResendVerificationHandler(dbConn, mailer))
-		auth.POST("/login", handlers.LoginHandler(dbConn, cfg.JWTAccessSecret, cfg.JWTRefreshSecret, time.Hour*24*7))
-		auth.POST("/refresh", handlers.RefreshHandler(dbConn, cfg.JWTAccessSecret, cfg.JWTRefreshSecret, time.Minute*15))
+		auth.POST("/login", handlers.LoginHandler(dbConn, cfg.JWTAccessSecret, cfg.JWTRefreshSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL))
+		auth.POST("/refresh", handlers.RefreshHandler(dbConn, cfg.JWTAccessSecret, cfg.JWTRefreshSecret, cfg.AccessTokenTTL))
 		auth.POST("/logout", handlers.LogoutHandler())
 		auth.POST("/request-password-reset", handlers.RequestPasswordResetHandler(dbConn, mailer))
 		auth.POST("/reset-password", handlers.ResetPasswordHandler(dbConn))
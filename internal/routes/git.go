@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/GordenArcher/mini-github/internal/config"
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/handlers"
+	"github.com/GordenArcher/mini-github/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterGitRoutes registers the Git smart HTTP endpoints under /:owner/:repo.git
+func RegisterGitRoutes(r *gin.Engine, dbConn *db.DB, cfg *config.Config) {
+	gitGroup := r.Group("/:owner/:repo")
+
+	gitGroup.Use(middleware.GitAuthMiddleware(dbConn, cfg.JWTAccessSecret))
+
+	gitGroup.GET("/info/refs", handlers.GitInfoRefs(dbConn))
+	gitGroup.POST("/git-upload-pack", handlers.GitUploadPack(dbConn))
+	gitGroup.POST("/git-receive-pack", handlers.GitReceivePack(dbConn))
+}
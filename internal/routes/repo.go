@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"github.com/GordenArcher/mini-github/internal/config"
 	"github.com/GordenArcher/mini-github/internal/db"
 	"github.com/GordenArcher/mini-github/internal/handlers"
 	"github.com/GordenArcher/mini-github/internal/middleware"
@@ -8,12 +9,62 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterRepoRoutes(r *gin.RouterGroup, dbConn *db.DB) {
+func RegisterRepoRoutes(r *gin.RouterGroup, dbConn *db.DB, cfg *config.Config) {
 	repoGroup := r.Group("/repos")
 
 	repoGroup.Use(middleware.AuthMiddleware())
 
-	repoGroup.POST("/create", handlers.CreateRepo(dbConn, "/Users/macbookpro/Desktop/mini-github-repos/"))
-	repoGroup.GET("/", handlers.ListUserRepos(dbConn))
-	repoGroup.GET("/:id", handlers.GetRepo(dbConn))
+	read := middleware.RequireScope(middleware.ScopeRepoRead)
+	write := middleware.RequireScope(middleware.ScopeRepoWrite)
+
+	repoGroup.POST("/create", write, handlers.CreateRepo(dbConn, cfg.PublicURL, cfg.SSHPort))
+	repoGroup.GET("/", read, handlers.ListUserRepos(dbConn))
+	repoGroup.GET("/deleted", write, handlers.ListDeletedRepos(dbConn, cfg.RepoRestoreWindow))
+	repoGroup.GET("/:owner/:name", read, handlers.GetRepo(dbConn))
+	repoGroup.PATCH("/:owner/:name", write, handlers.UpdateRepo(dbConn))
+	repoGroup.DELETE("/:owner/:name", write, handlers.DeleteRepo(dbConn, cfg.RepoRestoreWindow))
+	repoGroup.POST("/:owner/:name/restore", write, handlers.RestoreRepo(dbConn, cfg.RepoRestoreWindow))
+	repoGroup.POST("/:owner/:name/transfer", write, handlers.TransferRepo(dbConn))
+	repoGroup.DELETE("/:owner/:name/transfer", write, handlers.CancelTransfer(dbConn))
+	repoGroup.POST("/:owner/:name/transfer/accept", write, handlers.AcceptTransfer(dbConn, cfg.PublicURL, cfg.SSHPort))
+	repoGroup.GET("/:owner/:name/forks", read, handlers.ListForks(dbConn))
+	repoGroup.POST("/:owner/:name/forks", write, handlers.CreateFork(dbConn, cfg.PublicURL, cfg.SSHPort))
+	repoGroup.GET("/:owner/:name/tree/:ref", read, handlers.GetRepoTree(dbConn))
+	repoGroup.GET("/:owner/:name/tree/:ref/*path", read, handlers.GetRepoTree(dbConn))
+	repoGroup.GET("/:owner/:name/blob/:ref/*path", read, handlers.GetRepoBlob(dbConn))
+	repoGroup.GET("/:owner/:name/commits", read, handlers.ListRepoCommits(dbConn))
+	repoGroup.GET("/:owner/:name/commits/:sha", read, handlers.GetRepoCommit(dbConn))
+	repoGroup.GET("/:owner/:name/compare/*basehead", read, handlers.CompareRefs(dbConn))
+	repoGroup.GET("/:owner/:name/events", read, handlers.ListRepoEvents(dbConn))
+
+	repoGroup.GET("/:owner/:name/branches", read, handlers.ListBranches(dbConn))
+	repoGroup.POST("/:owner/:name/branches", write, handlers.CreateBranch(dbConn))
+	repoGroup.PATCH("/:owner/:name/branches/*branch", write, handlers.RenameBranch(dbConn))
+	repoGroup.DELETE("/:owner/:name/branches/*branch", write, handlers.DeleteBranch(dbConn))
+	repoGroup.PUT("/:owner/:name/default-branch", write, handlers.SetDefaultBranch(dbConn))
+
+	repoGroup.GET("/:owner/:name/branch-protections", write, handlers.ListBranchProtections(dbConn))
+	repoGroup.POST("/:owner/:name/branch-protections", write, handlers.CreateBranchProtection(dbConn))
+	repoGroup.PUT("/:owner/:name/branch-protections/:protection_id", write, handlers.UpdateBranchProtection(dbConn))
+	repoGroup.DELETE("/:owner/:name/branch-protections/:protection_id", write, handlers.DeleteBranchProtection(dbConn))
+
+	repoGroup.GET("/:owner/:name/policies", write, handlers.GetRepoPolicy(dbConn))
+	repoGroup.PUT("/:owner/:name/policies", write, handlers.UpdateRepoPolicy(dbConn))
+
+	repoGroup.GET("/:owner/:name/secret-alerts", write, handlers.ListSecretAlerts(dbConn))
+	repoGroup.PATCH("/:owner/:name/secret-alerts/:alert_id", write, handlers.UpdateSecretAlert(dbConn))
+
+	repoGroup.GET("/:owner/:name/hooks", write, handlers.ListWebhooks(dbConn, handlers.RepoWebhooks))
+	repoGroup.POST("/:owner/:name/hooks", write, handlers.CreateWebhook(dbConn, handlers.RepoWebhooks))
+	repoGroup.GET("/:owner/:name/hooks/:hook_id", write, handlers.GetWebhook(dbConn, handlers.RepoWebhooks))
+	repoGroup.PATCH("/:owner/:name/hooks/:hook_id", write, handlers.UpdateWebhook(dbConn, handlers.RepoWebhooks))
+	repoGroup.DELETE("/:owner/:name/hooks/:hook_id", write, handlers.DeleteWebhook(dbConn, handlers.RepoWebhooks))
+	repoGroup.POST("/:owner/:name/hooks/:hook_id/pings", write, handlers.PingWebhook(dbConn, handlers.RepoWebhooks))
+	repoGroup.GET("/:owner/:name/hooks/:hook_id/deliveries", write, handlers.ListWebhookDeliveries(dbConn, handlers.RepoWebhooks))
+	repoGroup.GET("/:owner/:name/hooks/:hook_id/deliveries/:delivery_id", write, handlers.GetWebhookDelivery(dbConn, handlers.RepoWebhooks))
+	repoGroup.POST("/:owner/:name/hooks/:hook_id/deliveries/:delivery_id/redeliver", write, handlers.RedeliverWebhook(dbConn, handlers.RepoWebhooks))
+
+	repoGroup.GET("/:owner/:name/tags", read, handlers.ListTags(dbConn))
+	repoGroup.POST("/:owner/:name/tags", write, handlers.CreateTag(dbConn))
+	repoGroup.DELETE("/:owner/:name/tags/*tag", write, handlers.DeleteTag(dbConn))
 }
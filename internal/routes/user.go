@@ -0,0 +1,44 @@
+package routes
+
+import (
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/handlers"
+	"github.com/GordenArcher/mini-github/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterUserRoutes registers endpoints for the authenticated user's account
+// and for looking up other users
+func RegisterUserRoutes(r *gin.RouterGroup, dbConn *db.DB) {
+	userGroup := r.Group("/user")
+
+	userGroup.Use(middleware.AuthMiddleware(), middleware.RequireScope(middleware.ScopeUser))
+
+	userGroup.GET("/keys", handlers.ListSSHKeys(dbConn))
+	userGroup.POST("/keys", handlers.AddSSHKey(dbConn))
+	userGroup.DELETE("/keys/:id", handlers.DeleteSSHKey(dbConn))
+
+	userGroup.GET("/tokens", handlers.ListPersonalAccessTokens(dbConn))
+	userGroup.POST("/tokens", handlers.CreatePersonalAccessToken(dbConn))
+	userGroup.DELETE("/tokens/:id", handlers.RevokePersonalAccessToken(dbConn))
+
+	userGroup.GET("/transfers", handlers.ListTransfers(dbConn))
+
+	// webhooks covering all of the user's repositories
+	userGroup.GET("/hooks", handlers.ListWebhooks(dbConn, handlers.UserWebhooks))
+	userGroup.POST("/hooks", handlers.CreateWebhook(dbConn, handlers.UserWebhooks))
+	userGroup.GET("/hooks/:hook_id", handlers.GetWebhook(dbConn, handlers.UserWebhooks))
+	userGroup.PATCH("/hooks/:hook_id", handlers.UpdateWebhook(dbConn, handlers.UserWebhooks))
+	userGroup.DELETE("/hooks/:hook_id", handlers.DeleteWebhook(dbConn, handlers.UserWebhooks))
+	userGroup.POST("/hooks/:hook_id/pings", handlers.PingWebhook(dbConn, handlers.UserWebhooks))
+	userGroup.GET("/hooks/:hook_id/deliveries", handlers.ListWebhookDeliveries(dbConn, handlers.UserWebhooks))
+	userGroup.GET("/hooks/:hook_id/deliveries/:delivery_id", handlers.GetWebhookDelivery(dbConn, handlers.UserWebhooks))
+	userGroup.POST("/hooks/:hook_id/deliveries/:delivery_id/redeliver", handlers.RedeliverWebhook(dbConn, handlers.UserWebhooks))
+
+	// other users' public activity
+	usersGroup := r.Group("/users")
+
+	usersGroup.Use(middleware.AuthMiddleware(), middleware.RequireScope(middleware.ScopeRepoRead))
+
+	usersGroup.GET("/:username/events", handlers.ListUserEvents(dbConn))
+}
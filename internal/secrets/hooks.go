@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/go-git/go-git/v5/plumbing"
+	"gorm.io/gorm/clause"
+)
+
+// Secret scanning modes
+const (
+	ModeAlert = "alert" // record an alert and accept the push
+	ModeBlock = "block" // refuse the push
+	ModeOff   = "off"
+)
+
+// IsValidMode reports whether mode is a secret scanning mode
+func IsValidMode(mode string) bool {
+	return mode == ModeAlert || mode == ModeBlock || mode == ModeOff
+}
+
+// BlockHook refuses pushes bringing in secrets to repositories in block mode
+func BlockHook(dbConn *db.DB) gitserver.PreReceiveHook {
+	return gitserver.PreReceiveFunc(func(ctx context.Context, p *gitserver.Push) error {
+		if m, err := repoMode(dbConn, p.Repo.ID); err != nil || m != ModeBlock {
+			return err
+		}
+
+		findings, err := scanPush(ctx, p)
+		if err != nil {
+			return err
+		}
+		var rejected []error
+		for ref, fs := range findings {
+			f := fs[0]
+			rejected = append(rejected, &gitserver.Rejection{
+				Ref:    ref,
+				Reason: fmt.Sprintf("commit %s: %s:%d: possible %s", f.Commit.String()[:7], f.Path, f.Line, f.Description()),
+			})
+		}
+		return errors.Join(rejected...)
+	})
+}
+
+// AlertHook records an alert for each secret a push brought into a repository in alert mode
+func AlertHook(dbConn *db.DB) gitserver.PostReceiveHook {
+	return gitserver.PostReceiveFunc(func(ctx context.Context, p *gitserver.Push) error {
+		if m, err := repoMode(dbConn, p.Repo.ID); err != nil || m != ModeAlert {
+			return err
+		}
+
+		findings, err := scanPush(ctx, p)
+		if err != nil {
+			return err
+		}
+		var alerts []db.SecretAlert
+		for ref, fs := range findings {
+			for _, f := range fs {
+				alerts = append(alerts, db.SecretAlert{
+					RepositoryID: p.Repo.ID,
+					CommitSHA:    f.Commit.String(),
+					Path:         f.Path,
+					Line:         f.Line,
+					Rule:         f.Rule,
+					Ref:          ref.String(),
+					State:        "open",
+				})
+			}
+		}
+		if len(alerts) == 0 {
+			return nil
+		}
+		return dbConn.Clauses(clause.OnConflict{DoNothing: true}).Create(&alerts).Error
+	})
+}
+
+func repoMode(dbConn *db.DB, repoID uint) (string, error) {
+	var cfg db.RepositoryPolicy
+	if err := dbConn.Where("repository_id = ?", repoID).Limit(1).Find(&cfg).Error; err != nil {
+		return "", err
+	}
+	if cfg.SecretScanning == "" {
+		return ModeAlert, nil
+	}
+	return cfg.SecretScanning, nil
+}
+
+// scanPush scans the commits each update brings in. A commit pushed to
+// several refs is reported against the first.
+func scanPush(ctx context.Context, p *gitserver.Push) (map[plumbing.ReferenceName][]Finding, error) {
+	findings := map[plumbing.ReferenceName][]Finding{}
+	scanned := map[plumbing.Hash]bool{}
+	for _, u := range p.Updates {
+		commits, err := p.NewCommits(u)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			if scanned[c.Hash] {
+				continue
+			}
+			scanned[c.Hash] = true
+
+			fs, err := ScanCommit(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+			findings[u.Name] = append(findings[u.Name], fs...)
+		}
+		if len(findings[u.Name]) == 0 {
+			delete(findings, u.Name)
+		}
+	}
+	return findings, nil
+}
@@ -0,0 +1,172 @@
+package secrets
+
+import (
+	"context"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Rule names, stored on alerts
+const (
+	RuleAWSAccessKey = "aws_access_key"
+	RuleAWSSecretKey = "aws_secret_key"
+	RulePrivateKey   = "private_key"
+	RuleSecretAssign = "secret_assignment"
+	RuleHighEntropy  = "high_entropy_token"
+)
+
+// generated keys are long and close to random; identifiers and hex digests aren't
+const (
+	highEntropyMinLen = 32
+	highEntropyBits   = 4.5
+)
+
+var descriptions = map[string]string{
+	RuleAWSAccessKey: "AWS access key ID",
+	RuleAWSSecretKey: "AWS secret access key",
+	RulePrivateKey:   "private key",
+	RuleSecretAssign: "hardcoded secret",
+	RuleHighEntropy:  "high entropy token",
+}
+
+var (
+	awsAccessKey = regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)
+	awsSecretKey = regexp.MustCompile(`(?i)aws.{0,20}(secret|private).{0,20}['"\s:=]+([0-9a-zA-Z/+]{40})\b`)
+	privateKey   = regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)
+	// JWT_ACCESS_SECRET=... in env files and shell scripts
+	envSecret = regexp.MustCompile(`^\s*(export\s+)?[A-Z0-9_]*(SECRET|PASSWORD|PASSWD|PASS|API_KEY|APIKEY|TOKEN)[A-Z0-9_]*\s*=\s*["']?([^\s"'#]{8,})`)
+	// "client_secret": "...", password = '...'; only quoted literals, so
+	// token := r.Header.Get(...) isn't flagged
+	quotedSecret = regexp.MustCompile(`(?i)(secret|passwd|password|api_?key|token)["']?\s*[:=]+\s*["']([^\s"']{8,})["']`)
+	tokenLike    = regexp.MustCompile(`[A-Za-z0-9+/_\-=]{32,}`)
+)
+
+// lock files are full of checksums that look like tokens
+var skippedFiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"poetry.lock":       true,
+	"composer.lock":     true,
+}
+
+// Finding is a line that looks like it holds a credential
+type Finding struct {
+	Commit plumbing.Hash
+	Path   string
+	Line   int
+	Rule   string
+}
+
+// Description is a human readable name for the kind of secret found
+func (f Finding) Description() string {
+	return descriptions[f.Rule]
+}
+
+// ScanCommit looks for credentials in the lines c adds compared to its first parent
+func ScanCommit(ctx context.Context, c *object.Commit) ([]Finding, error) {
+	patch, err := gitutil.CommitPatch(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, f := range gitutil.NewDiff(patch).Files {
+		if f.Binary || skippedFiles[path.Base(f.Path)] {
+			continue
+		}
+		for _, h := range f.Hunks {
+			for _, l := range h.Lines {
+				if l.Type != gitutil.LineAddition {
+					continue
+				}
+				if rule := ScanLine(l.Content); rule != "" {
+					findings = append(findings, Finding{Commit: c.Hash, Path: f.Path, Line: l.NewLine, Rule: rule})
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// ScanLine returns the rule line breaks, or "" if it looks clean
+func ScanLine(line string) string {
+	switch {
+	case privateKey.MatchString(line):
+		return RulePrivateKey
+	case awsAccessKey.MatchString(line):
+		return RuleAWSAccessKey
+	case awsSecretKey.MatchString(line):
+		return RuleAWSSecretKey
+	}
+
+	if m := envSecret.FindStringSubmatch(line); m != nil && !isPlaceholder(m[3]) {
+		return RuleSecretAssign
+	}
+	if m := quotedSecret.FindStringSubmatch(line); m != nil && !isPlaceholder(m[2]) {
+		return RuleSecretAssign
+	}
+
+	for _, tok := range tokenLike.FindAllString(line, -1) {
+		if looksRandom(tok) {
+			return RuleHighEntropy
+		}
+	}
+	return ""
+}
+
+// isPlaceholder reports whether a value is an obvious stand-in, like the ones
+// in .env.example, or a reference to somewhere else
+func isPlaceholder(v string) bool {
+	lower := strings.ToLower(v)
+	for _, p := range []string{"replace", "example", "changeme", "change_me", "your", "xxx", "<", "${", "{{", "process.env", "os.getenv", "getenv(", "dummy", "placeholder"} {
+		if strings.Contains(lower, p) {
+			return true
+		}
+	}
+	// repeated characters such as ******** or 00000000
+	return strings.Count(v, v[:1]) == len(v)
+}
+
+// looksRandom reports whether tok mixes letters and digits with the entropy
+// of a generated key. Hex digests stay below the threshold.
+func looksRandom(tok string) bool {
+	if len(tok) < highEntropyMinLen {
+		return false
+	}
+	var lower, upper, digit bool
+	for _, r := range tok {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	return lower && upper && digit && entropy(tok) >= highEntropyBits
+}
+
+// entropy is the Shannon entropy of s in bits per character
+func entropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,170 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/GordenArcher/mini-github/internal/log"
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/gliderlabs/ssh"
+	"go.uber.org/zap"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// context keys set during public key authentication
+const (
+	ctxUserID = "user_id"
+	ctxKeyID  = "ssh_key_id"
+)
+
+// Server serves git-upload-pack and git-receive-pack over SSH
+type Server struct {
+	db  *db.DB
+	srv *ssh.Server
+}
+
+// New creates an SSH server listening on addr. The host key is read from
+// hostKeyPath and generated there on first start.
+func New(dbConn *db.DB, addr, hostKeyPath string) (*Server, error) {
+	signer, err := loadHostKey(hostKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{db: dbConn}
+	s.srv = &ssh.Server{
+		Addr:             addr,
+		Handler:          s.handleSession,
+		PublicKeyHandler: s.authenticate,
+	}
+	s.srv.AddHostKey(signer)
+	return s, nil
+}
+
+// ListenAndServe accepts SSH connections until the server is closed
+func (s *Server) ListenAndServe() error {
+	return s.srv.ListenAndServe()
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// authenticate accepts any public key registered against a user who isn't disabled
+func (s *Server) authenticate(ctx ssh.Context, key ssh.PublicKey) bool {
+	var k db.SSHKey
+	if err := s.db.Where("fingerprint = ?", gossh.FingerprintSHA256(key)).First(&k).Error; err != nil {
+		return false
+	}
+	if disabled, err := s.db.UserDisabled(k.UserID); err != nil || disabled {
+		return false
+	}
+
+	ctx.SetValue(ctxUserID, k.UserID)
+	ctx.SetValue(ctxKeyID, k.ID)
+	return true
+}
+
+func (s *Server) handleSession(sess ssh.Session) {
+	args := sess.Command()
+	if len(args) != 2 || !gitserver.IsValidService(args[0]) {
+		fmt.Fprintln(sess.Stderr(), "mini-github does not provide shell access")
+		_ = sess.Exit(1)
+		return
+	}
+	service := args[0]
+
+	userID, _ := sess.Context().Value(ctxUserID).(uint)
+	keyID, _ := sess.Context().Value(ctxKeyID).(uint)
+	s.db.Model(&db.SSHKey{}).Where("id = ?", keyID).Update("last_used_at", time.Now())
+
+	owner, name, ok := parseRepoPath(args[1])
+	if !ok {
+		fmt.Fprintln(sess.Stderr(), "invalid repository path")
+		_ = sess.Exit(1)
+		return
+	}
+
+	// don't reveal whether a repository the user can't read exists. Renamed and
+	// transferred repositories stay reachable under their old paths.
+	repo, _, err := s.db.ResolveRepository(owner, name)
+	if err != nil || !repo.CanRead(userID) {
+		fmt.Fprintln(sess.Stderr(), "repository not found")
+		_ = sess.Exit(1)
+		return
+	}
+	if service == gitserver.ReceivePack && !repo.CanWrite(userID) {
+		fmt.Fprintln(sess.Stderr(), "permission denied")
+		_ = sess.Exit(1)
+		return
+	}
+
+	var protocol string
+	for _, env := range sess.Environ() {
+		if v, ok := strings.CutPrefix(env, "GIT_PROTOCOL="); ok {
+			protocol = v
+		}
+	}
+
+	if service == gitserver.ReceivePack {
+		err = gitserver.ServeReceivePackSession(sess.Context(), repo, userID, protocol, sess, sess)
+	} else {
+		err = gitserver.ServeSession(sess.Context(), service, storage.Path(repo.Path), protocol, sess, sess, sess.Stderr())
+	}
+	if err != nil {
+		log.Logger.Error("git ssh session failed", zap.String("service", service), zap.String("repo", repo.Path), zap.Error(err))
+		_ = sess.Exit(1)
+		return
+	}
+	_ = sess.Exit(0)
+}
+
+// parseRepoPath splits "/owner/name.git" (leading slash and suffix optional)
+func parseRepoPath(p string) (owner, name string, ok bool) {
+	p = strings.TrimSuffix(strings.TrimPrefix(p, "/"), ".git")
+	owner, name, ok = strings.Cut(p, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return owner, name, true
+}
+
+// loadHostKey reads the PEM encoded host key at path, generating an ed25519 key if it doesn't exist
+func loadHostKey(path string) (gossh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return gossh.ParsePrivateKey(data)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read ssh host key: %w", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ssh host key: %w", err)
+	}
+	block, err := gossh.MarshalPrivateKey(priv, "mini-github host key")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create ssh host key folder: %w", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write ssh host key: %w", err)
+	}
+	log.Logger.Info("generated ssh host key", zap.String("path", path))
+
+	return gossh.NewSignerFromKey(priv)
+}
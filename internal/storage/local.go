@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// Local keeps repositories as directories under a root on the local disk
+type Local struct {
+	root string
+}
+
+// NewLocal creates storage rooted at root. The directory is created on first use.
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// Path returns the directory of a repository. Repositories created before
+// storage was configurable are recorded with absolute paths, which are used as they are.
+func (l *Local) Path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(l.root, filepath.FromSlash(name))
+}
+
+// dir resolves a location that is about to be written to, refusing any that
+// would land outside the root
+func (l *Local) dir(name string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if name == "" || filepath.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrBadName, name)
+	}
+	return filepath.Join(l.root, clean), nil
+}
+
+func (l *Local) Create(ctx context.Context, name, defaultBranch string) (*git.Repository, error) {
+	dir, err := l.dir(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil, ErrExists
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+
+	if out, err := exec.CommandContext(ctx, "git", "init", "--bare", "-q", dir).CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("git init: %w: %s", err, out)
+	}
+
+	// HEAD follows the default branch
+	r, err := git.PlainOpen(dir)
+	if err == nil {
+		err = gitutil.SetHead(r, defaultBranch)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return r, nil
+}
+
+func (l *Local) Open(name string) (*git.Repository, error) {
+	s := filesystem.NewStorageWithOptions(osfs.New(l.Path(name)), cache.NewObjectLRUDefault(), filesystem.Options{
+		// forks list their parent's objects by absolute path
+		AlternatesFS: osfs.New("/"),
+	})
+	r, err := git.Open(s, nil)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, ErrNotFound
+	}
+	return r, err
+}
+
+func (l *Local) Fork(ctx context.Context, from, to string) (*git.Repository, error) {
+	src, err := filepath.Abs(l.Path(from))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := l.dir(to)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := l.Open(from)
+	if err != nil {
+		return nil, err
+	}
+	head, err := parent.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return nil, ErrExists
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+
+	// objects unreachable in the parent may still be reachable in its forks,
+	// so it must never prune them
+	if err := runGit(ctx, src, "config", "gc.pruneExpire", "never"); err != nil {
+		return nil, err
+	}
+	fail := func(err error) (*git.Repository, error) {
+		os.RemoveAll(dir)
+		l.removeEmptyParent(dir)
+		return nil, err
+	}
+	if err := runGit(ctx, "", "init", "--bare", "-q", dir); err != nil {
+		return fail(err)
+	}
+	if err := l.Borrow(to, from); err != nil {
+		return fail(err)
+	}
+	// every object is found through alternates, only refs are written
+	if err := runGit(ctx, dir, "fetch", "-q", "--no-tags", src, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"); err != nil {
+		return fail(err)
+	}
+
+	r, err := l.Open(to)
+	if err == nil {
+		err = r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, head.Target()))
+	}
+	if err != nil {
+		return fail(err)
+	}
+	return r, nil
+}
+
+func (l *Local) Borrow(name, from string) error {
+	src, err := filepath.Abs(l.Path(from))
+	if err != nil {
+		return err
+	}
+	objects := filepath.Join(src, "objects")
+	if _, err := os.Stat(objects); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	// go-git doesn't follow the alternates of an alternate, so whatever from
+	// borrows is listed here too
+	inherited, err := os.ReadFile(alternatesFile(src))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// written aside and renamed so readers never see half a list
+	file := alternatesFile(l.Path(name))
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, append([]byte(objects+"\n"), inherited...), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func (l *Local) Detach(ctx context.Context, name string) error {
+	dir := l.Path(name)
+	if _, err := os.Stat(alternatesFile(dir)); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	// without -l the new pack takes in borrowed objects too, -A keeps
+	// unreachable ones loose for gc to expire as usual
+	if err := runGit(ctx, dir, "repack", "-A", "-d", "-q"); err != nil {
+		return err
+	}
+	return os.Remove(alternatesFile(dir))
+}
+
+// alternatesFile lists the object directories a repository borrows from
+func alternatesFile(dir string) string {
+	return filepath.Join(dir, "objects", "info", "alternates")
+}
+
+// runGit runs a git command in dir, or in the working directory when dir is empty
+func runGit(ctx context.Context, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", args[0], err, out)
+	}
+	return nil
+}
+
+func (l *Local) Move(from, to string) error {
+	src := l.Path(from)
+	dst, err := l.dir(to)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(src); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return ErrExists
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return err
+	}
+	l.removeEmptyParent(src)
+	return nil
+}
+
+func (l *Local) Delete(name string) error {
+	var dir string
+	if filepath.IsAbs(name) {
+		dir = name
+	} else {
+		var err error
+		if dir, err = l.dir(name); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	l.removeEmptyParent(dir)
+	return nil
+}
+
+// removeEmptyParent drops an owner's directory once their last repository is gone
+func (l *Local) removeEmptyParent(dir string) {
+	parent := filepath.Dir(dir)
+	if parent != filepath.Clean(l.root) {
+		// fails harmlessly when the directory isn't empty
+		os.Remove(parent)
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+)
+
+var (
+	ErrExists   = errors.New("repository already exists")
+	ErrNotFound = errors.New("repository not found")
+	ErrBadName  = errors.New("invalid repository location")
+)
+
+// Storage keeps bare Git repositories. A repository is addressed by a
+// relative location such as "3/project.git", which is what the database
+// records in Repository.Path.
+type Storage interface {
+	// Create initializes an empty bare repository whose HEAD follows defaultBranch
+	Create(ctx context.Context, name, defaultBranch string) (*git.Repository, error)
+	// Open opens an existing repository
+	Open(name string) (*git.Repository, error)
+	// Path is the directory git commands run against for a repository
+	Path(name string) string
+	// Move gives a repository a new location
+	Move(from, to string) error
+	// Delete removes a repository and everything in it
+	Delete(name string) error
+	// Fork creates a repository with the branches, tags and HEAD of from that
+	// borrows its objects through Git alternates instead of copying them
+	Fork(ctx context.Context, from, to string) (*git.Repository, error)
+	// Borrow points a fork at the objects of its parent, now stored at from.
+	// Forks need it whenever their parent moves.
+	Borrow(name, from string) error
+	// Detach copies the objects a fork borrows into it and stops borrowing,
+	// so its parent can be removed
+	Detach(ctx context.Context, name string) error
+}
+
+// backend is the storage the server uses, set by Use at startup
+var backend Storage = NewLocal("data/repos")
+
+// Use makes s the storage for all repositories
+func Use(s Storage) {
+	backend = s
+}
+
+// Location is where a new repository of the given owner and name is stored
+func Location(ownerID uint, name string) string {
+	return fmt.Sprintf("%d/%s.git", ownerID, name)
+}
+
+// DeletedLocation is where a deleted repository is kept until it is restored or purged
+func DeletedLocation(repoID uint) string {
+	return fmt.Sprintf("deleted/%d.git", repoID)
+}
+
+func Create(ctx context.Context, name, defaultBranch string) (*git.Repository, error) {
+	return backend.Create(ctx, name, defaultBranch)
+}
+
+func Open(name string) (*git.Repository, error) {
+	return backend.Open(name)
+}
+
+func Path(name string) string {
+	return backend.Path(name)
+}
+
+func Move(from, to string) error {
+	return backend.Move(from, to)
+}
+
+func Delete(name string) error {
+	return backend.Delete(name)
+}
+
+func Fork(ctx context.Context, from, to string) (*git.Repository, error) {
+	return backend.Fork(ctx, from, to)
+}
+
+func Borrow(name, from string) error {
+	return backend.Borrow(name, from)
+}
+
+func Detach(ctx context.Context, name string) error {
+	return backend.Detach(ctx, name)
+}
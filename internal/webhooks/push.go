@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+)
+
+// maxPushCommits caps the commits listed in a push payload. commit_count has the full number.
+const maxPushCommits = 20
+
+// PushHook queues a push event for every ref a push changed, and a
+// branch_deleted event for each branch it deleted
+func PushHook(dbConn *db.DB) gitserver.PostReceiveHook {
+	return gitserver.PostReceiveFunc(func(ctx context.Context, p *gitserver.Push) error {
+		// a ref that fails doesn't keep the events of the others from going out
+		var errs []error
+		for _, u := range p.Updates {
+			if err := emitPush(dbConn, p, u); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", u.Name, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+func emitPush(dbConn *db.DB, p *gitserver.Push, u gitserver.RefUpdate) error {
+	payload, err := PushPayload(p, u)
+	if err != nil {
+		return err
+	}
+	if err := Emit(dbConn, p.Repo, p.PusherID, EventPush, payload); err != nil {
+		return err
+	}
+	if u.IsDelete() && u.Name.IsBranch() {
+		return EmitBranchDeleted(dbConn, p.Repo, p.PusherID, u.Name.Short(), u.Old.String())
+	}
+	return nil
+}
+
+// PushPayload returns the fields of the push event for u
+func PushPayload(p *gitserver.Push, u gitserver.RefUpdate) (map[string]any, error) {
+	newCommits, err := p.NewCommits(u)
+	if err != nil {
+		return nil, err
+	}
+	forced, err := isForced(p, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// oldest first, like the order they were made in
+	commits := []gitutil.CommitInfo{}
+	for i := len(newCommits) - 1; i >= 0 && len(commits) < maxPushCommits; i-- {
+		commits = append(commits, gitutil.NewCommitInfo(newCommits[i]))
+	}
+
+	return map[string]any{
+		"ref":          u.Name.String(),
+		"before":       u.Old.String(),
+		"after":        u.New.String(),
+		"created":      u.IsCreate(),
+		"deleted":      u.IsDelete(),
+		"forced":       forced,
+		"commits":      commits,
+		"commit_count": len(newCommits),
+	}, nil
+}
+
+// isForced reports whether u moves a ref to a commit that doesn't contain the
+// old one. Annotated tags are peeled first; a side that doesn't lead to a
+// commit, such as a tag of a tree, isn't counted as forced.
+func isForced(p *gitserver.Push, u gitserver.RefUpdate) (bool, error) {
+	if u.IsCreate() || u.IsDelete() {
+		return false, nil
+	}
+	oldCommit, err := gitutil.PeelToCommit(p.Git, u.Old)
+	if err != nil {
+		return false, nil
+	}
+	newCommit, err := gitutil.PeelToCommit(p.Git, u.New)
+	if err != nil {
+		return false, nil
+	}
+	ff, err := oldCommit.IsAncestor(newCommit)
+	return !ff, err
+}
+
+// EmitBranchDeleted queues a branch_deleted event. sha is the branch's last commit.
+func EmitBranchDeleted(dbConn *db.DB, repo *db.Repository, senderID uint, branch, sha string) error {
+	return Emit(dbConn, repo, senderID, EventBranchDeleted, map[string]any{
+		"branch": branch,
+		"sha":    sha,
+	})
+}
@@ -0,0 +1,184 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+)
+
+// Events a webhook can subscribe to. Ping is sent to every new hook.
+const (
+	EventPush              = "push"
+	EventRepositoryCreated = "repository_created"
+	EventBranchDeleted     = "branch_deleted"
+	EventPing              = "ping"
+	EventAll               = "*"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// IsValidEvent reports whether a hook may subscribe to event
+func IsValidEvent(event string) bool {
+	switch event {
+	case EventPush, EventRepositoryCreated, EventBranchDeleted, EventAll:
+		return true
+	}
+	return false
+}
+
+// Subscribes reports whether hook wants event
+func Subscribes(hook *db.Webhook, event string) bool {
+	if event == EventPing {
+		return true
+	}
+	for _, e := range hook.Events {
+		if e == event || e == EventAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository is how payloads describe a repository
+type Repository struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Owner         string `json:"owner"`
+	Visibility    string `json:"visibility"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// User is how payloads describe a user
+type User struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// Emit queues event for the active hooks on repo and on all of its owner's
+// repositories. fields are sent next to repository and sender.
+func Emit(dbConn *db.DB, repo *db.Repository, senderID uint, event string, fields map[string]any) error {
+	var hooks []db.Webhook
+	err := dbConn.Where("active = ? AND owner_id = ? AND (repository_id = ? OR repository_id IS NULL)", true, repo.OwnerID, repo.ID).
+		Find(&hooks).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range hooks {
+		if !Subscribes(&hooks[i], event) {
+			continue
+		}
+		if err := Queue(dbConn, &hooks[i], repo, senderID, event, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Queue records a pending delivery of event to hook for the worker to send
+func Queue(dbConn *db.DB, hook *db.Webhook, repo *db.Repository, senderID uint, event string, fields map[string]any) error {
+	if repo.Owner.ID == 0 {
+		if err := dbConn.First(&repo.Owner, repo.OwnerID).Error; err != nil {
+			return err
+		}
+	}
+	var sender db.User
+	if err := dbConn.First(&sender, senderID).Error; err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"repository": Repository{
+			ID:            repo.ID,
+			Name:          repo.Name,
+			FullName:      repo.Owner.Username + "/" + repo.Name,
+			Owner:         repo.Owner.Username,
+			Visibility:    repo.Visibility,
+			DefaultBranch: repo.DefaultBranch,
+		},
+		"sender": User{ID: sender.ID, Username: sender.Username},
+	}
+	for k, v := range fields {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	d, err := newDelivery(hook.ID, event, string(body))
+	if err != nil {
+		return err
+	}
+	return enqueue(dbConn, d)
+}
+
+// Redeliver queues a fresh delivery of a previous one's payload
+func Redeliver(dbConn *db.DB, previous *db.WebhookDelivery) (*db.WebhookDelivery, error) {
+	d, err := newDelivery(previous.WebhookID, previous.Event, previous.RequestBody)
+	if err != nil {
+		return nil, err
+	}
+	d.RedeliveryOfID = &previous.ID
+	if err := enqueue(dbConn, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func newDelivery(hookID uint, event, body string) (*db.WebhookDelivery, error) {
+	guid, err := newGUID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &db.WebhookDelivery{
+		WebhookID:     hookID,
+		GUID:          guid,
+		Event:         event,
+		Status:        StatusPending,
+		NextAttemptAt: &now,
+		RequestBody:   body,
+	}, nil
+}
+
+// newGUID returns a random UUID in the usual 8-4-4-4-12 form
+func newGUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// Ping queues a ping to hook so its receiver can be checked
+func Ping(dbConn *db.DB, hook *db.Webhook) (*db.WebhookDelivery, error) {
+	body, err := json.Marshal(map[string]any{
+		"hook_id": hook.ID,
+		"hook":    hook,
+		"zen":     "Keep it logically awesome.",
+	})
+	if err != nil {
+		return nil, err
+	}
+	d, err := newDelivery(hook.ID, EventPing, string(body))
+	if err != nil {
+		return nil, err
+	}
+	if err := enqueue(dbConn, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,266 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/jobs"
+	"github.com/GordenArcher/mini-github/internal/redis"
+	"gorm.io/gorm"
+)
+
+// Delivery headers
+const (
+	HeaderEvent     = "X-MiniGitHub-Event"
+	HeaderDelivery  = "X-MiniGitHub-Delivery"
+	HeaderSignature = "X-Hub-Signature-256"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it fails
+	MaxAttempts = 5
+
+	// JobDeliver is the job type that makes one attempt at a delivery
+	JobDeliver = "webhooks.deliver"
+
+	retryBase       = 30 * time.Second
+	retryMax        = 30 * time.Minute
+	deliveryTimeout = 10 * time.Second
+	// response bodies beyond this are cut off in the delivery history
+	maxResponseBody = 64 << 10
+)
+
+// Sign returns the X-Hub-Signature-256 value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is how long to wait before retrying after the given failed attempt
+func Backoff(attempt int) time.Duration {
+	d := retryBase << (attempt - 1)
+	if d > retryMax || d <= 0 {
+		return retryMax
+	}
+	return d
+}
+
+type deliverJob struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// keyQueued is set in Redis once pending deliveries have jobs there. It is
+// missing after upgrading from the worker that polled the database, and after
+// Redis lost its data, jobs included.
+const keyQueued = "webhooks:pending-queued"
+
+// enqueue records d and queues its first attempt. The job is queued once the
+// delivery is committed, so a worker can't look for it too early.
+func enqueue(dbConn *db.DB, d *db.WebhookDelivery) error {
+	if err := dbConn.Create(d).Error; err != nil {
+		return err
+	}
+	if err := jobs.Enqueue(context.Background(), jobs.QueueWebhooks, JobDeliver, deliverJob{DeliveryID: d.ID}); err != nil {
+		dbConn.Delete(d)
+		return err
+	}
+	return nil
+}
+
+// QueuePending queues the pending deliveries that have no job in Redis,
+// which is all of them when keyQueued is missing. It runs at startup.
+func QueuePending(ctx context.Context, dbConn *db.DB) error {
+	started := time.Now()
+	first, err := redis.Client.SetNX(ctx, keyQueued, started.Unix(), 0).Result()
+	if err != nil || !first {
+		return err
+	}
+
+	// deliveries made from now on are queued as they are made
+	var pending []db.WebhookDelivery
+	err = dbConn.Where("status = ? AND created_at < ?", StatusPending, started).Order("id").Find(&pending).Error
+	for i := 0; err == nil && i < len(pending); i++ {
+		job := deliverJob{DeliveryID: pending[i].ID}
+		if at := pending[i].NextAttemptAt; at != nil && at.After(started) {
+			err = jobs.Schedule(ctx, jobs.QueueWebhooks, JobDeliver, job, *at)
+		} else {
+			err = jobs.Enqueue(ctx, jobs.QueueWebhooks, JobDeliver, job)
+		}
+	}
+	if err != nil {
+		// try again at the next start
+		redis.Client.Del(ctx, keyQueued)
+		return err
+	}
+	return nil
+}
+
+// errBlockedAddress is returned for receivers on the server's own network
+var errBlockedAddress = errors.New("webhooks can't be delivered to loopback, link-local or private addresses")
+
+// NewClient returns the HTTP client deliveries are sent with. Receiver
+// addresses are checked as connections are made, after DNS has been resolved,
+// so a name can't point a hook at the server's own network. Redirects aren't
+// followed since they could lead there too.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allowedAddress(addr.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   deliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func allowedAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !(ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast())
+}
+
+// HandleDeliver is the job handler that attempts a pending delivery. Failed
+// attempts are rescheduled here rather than by the job queue so that the
+// delivery history shows each one.
+func HandleDeliver(dbConn *db.DB) jobs.Handler {
+	client := NewClient()
+	return func(ctx context.Context, j *jobs.Job) error {
+		var job deliverJob
+		if err := j.Decode(&job); err != nil {
+			return err
+		}
+
+		var d db.WebhookDelivery
+		if err := dbConn.First(&d, job.DeliveryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// retried in case the delivery isn't visible yet; when its
+				// hook was deleted along with it, the job runs out of attempts
+				return fmt.Errorf("delivery %d not found", job.DeliveryID)
+			}
+			return err
+		}
+		if d.Status != StatusPending {
+			return nil
+		}
+		var hook db.Webhook
+		if err := dbConn.First(&hook, d.WebhookID).Error; err != nil {
+			return err
+		}
+
+		Attempt(ctx, client, &hook, &d)
+		if err := dbConn.Save(&d).Error; err != nil {
+			return err
+		}
+		if d.NextAttemptAt != nil {
+			return jobs.Schedule(ctx, jobs.QueueWebhooks, JobDeliver, job, *d.NextAttemptAt)
+		}
+		return nil
+	}
+}
+
+// Attempt sends d to hook and sets its status, or when to try again, from
+// the outcome. Nothing is sent once the hook has been deactivated, since its
+// owner may have turned it off because the receiver can't be trusted.
+func Attempt(ctx context.Context, client *http.Client, hook *db.Webhook, d *db.WebhookDelivery) {
+	if !hook.Active {
+		d.Status = StatusFailed
+		d.Error = "webhook inactive"
+		d.NextAttemptAt = nil
+		return
+	}
+
+	Send(ctx, client, hook, d)
+
+	switch {
+	case d.Error == "":
+		d.Status = StatusSucceeded
+		d.NextAttemptAt = nil
+	case d.Attempts >= MaxAttempts:
+		d.Status = StatusFailed
+		d.NextAttemptAt = nil
+	default:
+		next := time.Now().Add(Backoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+}
+
+// Send posts d's payload to hook and records the request and response on d.
+// d.Error is empty when the receiver answered with a 2xx status.
+func Send(ctx context.Context, client *http.Client, hook *db.Webhook, d *db.WebhookDelivery) {
+	d.Attempts++
+	d.Error = ""
+	d.ResponseStatus = 0
+	d.ResponseHeaders = nil
+	d.ResponseBody = ""
+
+	body := []byte(d.RequestBody)
+	d.RequestHeaders = map[string]string{
+		"Content-Type": "application/json",
+		"User-Agent":   "mini-github-webhooks",
+		HeaderEvent:    d.Event,
+		HeaderDelivery: d.GUID,
+	}
+	if hook.Secret != "" {
+		d.RequestHeaders[HeaderSignature] = Sign(hook.Secret, body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	for k, v := range d.RequestHeaders {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	d.DurationMS = time.Since(start).Milliseconds()
+	now := time.Now()
+	d.DeliveredAt = &now
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	d.ResponseStatus = resp.StatusCode
+	d.ResponseBody = string(respBody)
+	d.ResponseHeaders = map[string]string{}
+	for k, v := range resp.Header {
+		d.ResponseHeaders[k] = strings.Join(v, ", ")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		d.Error = fmt.Sprintf("receiver responded with %d", resp.StatusCode)
+	}
+}
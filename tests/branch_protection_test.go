@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBranchProtectionMatches(t *testing.T) {
+	cases := []struct {
+		pattern, branch string
+		want            bool
+	}{
+		{"main", "main", true},
+		{"main", "main2", false},
+		{"release/*", "release/1.0", true},
+		{"release/*", "release/1.0/hotfix", false},
+		{"*", "feature/login", false},
+		{"[", "main", false},
+	}
+	for _, tc := range cases {
+		rule := db.BranchProtection{Pattern: tc.pattern}
+		assert.Equal(t, tc.want, rule.Matches(tc.branch), "%s matching %s", tc.pattern, tc.branch)
+	}
+}
+
+func TestBranchProtectionAllowsPusher(t *testing.T) {
+	open := db.BranchProtection{Pattern: "main"}
+	assert.True(t, open.AllowsPusher(7))
+
+	restricted := db.BranchProtection{Pattern: "main", RestrictPushes: true, AllowedPusherIDs: []uint{3}}
+	assert.True(t, restricted.AllowsPusher(3))
+	assert.False(t, restricted.AllowsPusher(7))
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+
+	assert.Equal(t, config.EnvDevelopment, cfg.Env)
+	assert.Equal(t, "http://localhost:8080", cfg.PublicURL)
+	assert.Equal(t, 15*time.Minute, cfg.AccessTokenTTL)
+	assert.Equal(t, 7*24*time.Hour, cfg.RepoRestoreWindow)
+	assert.True(t, filepath.IsAbs(cfg.ReposPath))
+	assert.Equal(t, "log", cfg.MailTransport)
+}
+
+func TestLoadYAMLWithEnvOverride(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+server_port: 9000
+public_url: https://git.example.com/
+repos_path: /srv/repos
+access_token_ttl: 5m
+rate_limit: 100
+smtp_host: smtp.example.com
+`)
+	t.Setenv("RATE_LIMIT", "50")
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, "9000", cfg.ServerPort)
+	assert.Equal(t, "https://git.example.com", cfg.PublicURL)
+	assert.Equal(t, "/srv/repos", cfg.ReposPath)
+	assert.Equal(t, 5*time.Minute, cfg.AccessTokenTTL)
+	assert.Equal(t, int64(50), cfg.RateLimit)
+	assert.Equal(t, "smtp", cfg.MailTransport)
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfig(t, "config.toml", `
+job_workers = 8
+refresh_token_ttl = "48h"
+`)
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 8, cfg.JobWorkers)
+	assert.Equal(t, 48*time.Hour, cfg.RefreshTokenTTL)
+}
+
+func TestLoadRejectsBadFiles(t *testing.T) {
+	_, err := config.Load(writeConfig(t, "config.yaml", "jwt_acess_secret: typo\n"))
+	assert.ErrorContains(t, err, "unknown setting jwt_acess_secret")
+
+	_, err = config.Load(writeConfig(t, "config.yaml", "access_token_ttl: 900\n"))
+	assert.ErrorContains(t, err, "invalid duration")
+
+	_, err = config.Load(writeConfig(t, "config.yaml", "mail_transport: pigeon\nserver_port: 0\n"))
+	assert.ErrorContains(t, err, "mail_transport must be")
+	assert.ErrorContains(t, err, "server_port must be a port number")
+}
+
+func TestProductionRefusesDefaultSecrets(t *testing.T) {
+	t.Setenv("ENV", config.EnvProduction)
+
+	_, err := config.Load("")
+	assert.ErrorContains(t, err, "jwt_access_secret must be a random value")
+	assert.ErrorContains(t, err, "jwt_refresh_secret must be a random value")
+	assert.ErrorContains(t, err, "log mail transport can't be used in production")
+
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("JWT_ACCESS_SECRET", "9c1f0b6e4d2a8f7e3b5c1d9a0e6f4b2c")
+	t.Setenv("JWT_REFRESH_SECRET", "3e7a9d1c5b0f8e2a4c6d0b9f7e1a3c5d")
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.True(t, cfg.IsProduction())
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// commitFiles writes files into the worktree and commits them
+func commitFiles(t *testing.T, r *git.Repository, msg string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+	for name, content := range files {
+		require.NoError(t, util.WriteFile(wt.Filesystem, name, []byte(content), 0644))
+		_, err := wt.Add(name)
+		require.NoError(t, err)
+	}
+	sig := &object.Signature{Name: "Ada", Email: "ada@example.com", When: time.Now()}
+	hash, err := wt.Commit(msg, &git.CommitOptions{Author: sig, Committer: sig})
+	require.NoError(t, err)
+	return hash
+}
+
+func lines(from, to int, replace map[int]string) string {
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		if s, ok := replace[i]; ok {
+			b.WriteString(s + "\n")
+			continue
+		}
+		b.WriteString(strings.Repeat("x", i) + "\n")
+	}
+	return b.String()
+}
+
+func TestCommitDiffHunks(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+
+	commitFiles(t, r, "initial", map[string]string{"f.txt": lines(1, 30, nil)})
+	head := commitFiles(t, r, "edit", map[string]string{
+		"f.txt":   lines(1, 30, map[int]string{2: "two", 20: "twenty"}),
+		"new.txt": "hello\n",
+	})
+
+	cmt, err := r.CommitObject(head)
+	require.NoError(t, err)
+	patch, err := gitutil.CommitPatch(context.Background(), cmt)
+	require.NoError(t, err)
+	diff := gitutil.NewDiff(patch)
+
+	assert.Equal(t, 2, diff.ChangedFiles)
+	assert.Equal(t, 3, diff.Additions)
+	assert.Equal(t, 2, diff.Deletions)
+
+	files := map[string]gitutil.FileDiff{}
+	for _, f := range diff.Files {
+		files[f.Path] = f
+	}
+
+	added := files["new.txt"]
+	assert.Equal(t, gitutil.StatusAdded, added.Status)
+	require.Len(t, added.Hunks, 1)
+	assert.Equal(t, "@@ -0,0 +1,1 @@", added.Hunks[0].Header)
+
+	modified := files["f.txt"]
+	assert.Equal(t, gitutil.StatusModified, modified.Status)
+	require.Len(t, modified.Hunks, 2)
+	assert.Equal(t, "@@ -1,5 +1,5 @@", modified.Hunks[0].Header)
+	assert.Equal(t, "@@ -17,7 +17,7 @@", modified.Hunks[1].Header)
+	assert.Equal(t, gitutil.DiffLine{Type: gitutil.LineAddition, Content: "twenty", NewLine: 20}, modified.Hunks[1].Lines[4])
+}
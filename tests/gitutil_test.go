@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/gitutil"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrailers(t *testing.T) {
+	msg := "Fix login redirect\n\nThe redirect dropped the query string.\n\nSigned-off-by: Ada <ada@example.com>\nCo-authored-by: Bob\n  <bob@example.com>\n"
+	assert.Equal(t, []gitutil.Trailer{
+		{Key: "Signed-off-by", Value: "Ada <ada@example.com>"},
+		{Key: "Co-authored-by", Value: "Bob <bob@example.com>"},
+	}, gitutil.ParseTrailers(msg))
+
+	// a subject line alone never has trailers
+	assert.Empty(t, gitutil.ParseTrailers("Fixes: nothing"))
+
+	// prose in the last paragraph means there are no trailers
+	assert.Empty(t, gitutil.ParseTrailers("Subject\n\nSee: the docs\nand more prose"))
+}
+
+// commitAt stores an empty commit with the given parents, committed at minute
+// of a fixed day so walks ordered by date are predictable
+func commitAt(t *testing.T, r *git.Repository, msg string, minute int, parents ...plumbing.Hash) plumbing.Hash {
+	t.Helper()
+	tree := r.Storer.NewEncodedObject()
+	require.NoError(t, (&object.Tree{}).Encode(tree))
+	treeHash, err := r.Storer.SetEncodedObject(tree)
+	require.NoError(t, err)
+
+	when := time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC)
+	sig := object.Signature{Name: "Ada", Email: "ada@example.com", When: when}
+	commit := &object.Commit{Author: sig, Committer: sig, Message: msg, TreeHash: treeHash, ParentHashes: parents}
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(t, commit.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return hash
+}
+
+func messages(commits []*object.Commit) []string {
+	var msgs []string
+	for _, c := range commits {
+		msgs = append(msgs, c.Message)
+	}
+	return msgs
+}
+
+func TestLogPagesResumeAcrossMerges(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(t, err)
+
+	root := commitAt(t, r, "root", 0)
+	m1 := commitAt(t, r, "m1", 1, root)
+	s1 := commitAt(t, r, "s1", 2, root)
+	m2 := commitAt(t, r, "m2", 3, m1)
+	merge := commitAt(t, r, "merge", 4, m2, s1)
+
+	// m1 is only reachable through the first parent of the merge, but older
+	// than s1 which starts the second page
+	cursor := gitutil.LogCursor{From: merge}
+	var got []string
+	for pages := 0; pages < 10; pages++ {
+		page, next, err := gitutil.LogPage(r, &git.LogOptions{From: cursor.From, Order: git.LogOrderCommitterTime}, cursor.Skip, 2,
+			func(*object.Commit) bool { return true })
+		require.NoError(t, err)
+		got = append(got, messages(page)...)
+		if next == 0 {
+			break
+		}
+		cursor.Skip = next
+
+		parsed, err := gitutil.ParseLogCursor(cursor.String())
+		require.NoError(t, err)
+		assert.Equal(t, cursor, parsed)
+	}
+	assert.Equal(t, []string{"merge", "m2", "s1", "m1", "root"}, got)
+
+	// filtered commits still count towards the skip
+	page, next, err := gitutil.LogPage(r, &git.LogOptions{From: merge, Order: git.LogOrderCommitterTime}, 0, 1,
+		func(c *object.Commit) bool { return c.Message != "m2" })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"merge"}, messages(page))
+	assert.Equal(t, 2, next)
+
+	for _, bad := range []string{"", "abc.1", merge.String(), merge.String() + ".-1", merge.String() + ".x"} {
+		_, err := gitutil.ParseLogCursor(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestRevList(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(t, err)
+	commit := func(h plumbing.Hash) *object.Commit {
+		c, err := r.CommitObject(h)
+		require.NoError(t, err)
+		return c
+	}
+
+	// root - a - b - c
+	root := commitAt(t, r, "root", 0)
+	a := commitAt(t, r, "a", 1, root)
+	b := commitAt(t, r, "b", 2, a)
+	c := commitAt(t, r, "c", 3, b)
+
+	got, err := gitutil.RevList(commit(c), commit(a))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, messages(got))
+
+	ahead, behind, err := gitutil.AheadBehind(commit(a), commit(c))
+	require.NoError(t, err)
+	assert.Equal(t, 2, ahead)
+	assert.Equal(t, 0, behind)
+
+	// x2 and y2 both merge x1 and y1, so neither side has a single merge base
+	x1 := commitAt(t, r, "x1", 4, c)
+	y1 := commitAt(t, r, "y1", 5, c)
+	x2 := commitAt(t, r, "x2", 6, x1, y1)
+	y2 := commitAt(t, r, "y2", 7, y1, x1)
+	x3 := commitAt(t, r, "x3", 8, x2)
+	y3 := commitAt(t, r, "y3", 9, y2)
+
+	got, err = gitutil.RevList(commit(x3), commit(y3))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x3", "x2"}, messages(got))
+
+	ahead, behind, err = gitutil.AheadBehind(commit(y3), commit(x3))
+	require.NoError(t, err)
+	assert.Equal(t, 2, ahead)
+	assert.Equal(t, 2, behind)
+
+	// a base from unrelated history excludes nothing
+	o1 := commitAt(t, r, "o1", 10)
+	o2 := commitAt(t, r, "o2", 11, o1)
+
+	got, err = gitutil.RevList(commit(c), commit(o2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b", "a", "root"}, messages(got))
+
+	ahead, behind, err = gitutil.AheadBehind(commit(o2), commit(c))
+	require.NoError(t, err)
+	assert.Equal(t, 4, ahead)
+	assert.Equal(t, 2, behind)
+}
@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobBackoff(t *testing.T) {
+	assert.Equal(t, 15*time.Second, jobs.Backoff(1))
+	assert.Equal(t, 30*time.Second, jobs.Backoff(2))
+	assert.Equal(t, 4*time.Minute, jobs.Backoff(5))
+	assert.Equal(t, time.Hour, jobs.Backoff(20))
+	assert.Equal(t, time.Hour, jobs.Backoff(200))
+}
+
+func TestJobDecode(t *testing.T) {
+	j := &jobs.Job{Payload: json.RawMessage(`{"delivery_id": 42}`)}
+	var payload struct {
+		DeliveryID uint `json:"delivery_id"`
+	}
+	require.NoError(t, j.Decode(&payload))
+	assert.Equal(t, uint(42), payload.DeliveryID)
+}
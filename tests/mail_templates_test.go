@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GordenArcher/mini-github/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderVerifyEmail(t *testing.T) {
+	tmpl := mail.NewTemplates("", "https://git.example.com/")
+
+	msg, err := tmpl.Render("verify_email", "", map[string]any{"Username": "ada", "Token": "abc123"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Verify your Mini-GitHub account", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi ada,")
+	assert.Contains(t, msg.Text, "https://git.example.com/api/v1/auth/verify?token=abc123")
+	assert.Contains(t, msg.HTML, `href="https://git.example.com/api/v1/auth/verify?token=abc123"`)
+	assert.NotContains(t, msg.HTML, "localhost")
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	tmpl := mail.NewTemplates("", "https://git.example.com")
+
+	msg, err := tmpl.Render("password_reset", "en", map[string]any{"Username": "<b>x</b>", "Token": "t"})
+	require.NoError(t, err)
+
+	assert.Contains(t, msg.HTML, "&lt;b&gt;x&lt;/b&gt;")
+	assert.Contains(t, msg.Text, "<b>x</b>")
+}
+
+func TestRenderMissingData(t *testing.T) {
+	tmpl := mail.NewTemplates("", "https://git.example.com")
+
+	_, err := tmpl.Render("verify_email", "en", map[string]any{"Username": "ada"})
+	assert.Error(t, err)
+
+	_, err = tmpl.Render("no_such_email", "en", nil)
+	assert.Error(t, err)
+}
+
+func TestRenderLocaleOverrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pt"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pt", "verify_email.txt"),
+		[]byte(`{{define "subject"}}Verifique sua conta{{end}}{{define "content"}}Olá {{.Username}}{{end}}`), 0o644))
+
+	tmpl := mail.NewTemplates(dir, "https://git.example.com")
+
+	// pt-BR falls back to pt for the text part and to the built-in English HTML
+	msg, err := tmpl.Render("verify_email", "pt-BR", map[string]any{"Username": "ada", "Token": "t"})
+	require.NoError(t, err)
+	assert.Equal(t, "Verifique sua conta", msg.Subject)
+	assert.Contains(t, msg.Text, "Olá ada")
+	assert.Contains(t, msg.Text, "Sent by Mini-GitHub")
+	assert.Contains(t, msg.HTML, "Verify email")
+
+	// other locales are untouched
+	msg, err = tmpl.Render("verify_email", "de", map[string]any{"Username": "ada", "Token": "t"})
+	require.NoError(t, err)
+	assert.Equal(t, "Verify your Mini-GitHub account", msg.Subject)
+
+	// a locale can't point outside the template directory
+	msg, err = tmpl.Render("verify_email", "../pt", map[string]any{"Username": "ada", "Token": "t"})
+	require.NoError(t, err)
+	assert.Equal(t, "Verify your Mini-GitHub account", msg.Subject)
+}
@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"bufio"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/log"
+	minimail "github.com/GordenArcher/mini-github/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type recordingTransport struct {
+	sent []*minimail.Message
+}
+
+func (t *recordingTransport) Send(msg *minimail.Message) error {
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+func TestMailerSetsFrom(t *testing.T) {
+	rec := &recordingTransport{}
+	m := minimail.New("noreply@example.com", rec, nil)
+
+	require.NoError(t, m.Send(&minimail.Message{To: "ada@example.com", Subject: "hi"}))
+	require.Len(t, rec.sent, 1)
+	assert.Equal(t, "noreply@example.com", rec.sent[0].From)
+}
+
+func TestFileTransportWritesMultipart(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	tr, err := minimail.NewFileTransport(dir)
+	require.NoError(t, err)
+
+	msg := &minimail.Message{From: "noreply@example.com", To: "ada@example.com", Subject: "Hello", Text: "plain body", HTML: "<p>html body</p>"}
+	require.NoError(t, tr.Send(msg))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	parsed, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+	assert.Equal(t, "ada@example.com", parsed.Header.Get("To"))
+	assert.Equal(t, "Hello", parsed.Header.Get("Subject"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	var types []string
+	r := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err != nil {
+			break
+		}
+		ct, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		types = append(types, ct)
+	}
+	assert.Equal(t, []string{"text/plain", "text/html"}, types)
+}
+
+// fakeSMTP accepts mail without TLS or auth and counts connections and messages
+type fakeSMTP struct {
+	mu       sync.Mutex
+	conns    int
+	messages []string
+}
+
+func (s *fakeSMTP) serve(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch cmd {
+		case "EHLO":
+			reply("250 fake")
+		case "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				body.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, body.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPTransportReusesConnection(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	server := &fakeSMTP{}
+	go server.serve(l)
+
+	port := l.Addr().(*net.TCPAddr).Port
+	tr, err := minimail.NewSMTPTransport(minimail.SMTPConfig{Host: "127.0.0.1", Port: port, TLS: minimail.TLSNone})
+	require.NoError(t, err)
+	defer tr.Close()
+
+	for _, to := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		require.NoError(t, tr.Send(&minimail.Message{From: "noreply@example.com", To: to, Subject: "hi", Text: "t", HTML: "h"}))
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, 1, server.conns)
+	require.Len(t, server.messages, 3)
+	assert.Contains(t, server.messages[2], "To: c@example.com")
+}
+
+func TestSMTPTransportRequiresStartTLS(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go (&fakeSMTP{}).serve(l)
+
+	port := l.Addr().(*net.TCPAddr).Port
+	tr, err := minimail.NewSMTPTransport(minimail.SMTPConfig{Host: "127.0.0.1", Port: port})
+	require.NoError(t, err)
+	err = tr.Send(&minimail.Message{From: "noreply@example.com", To: "a@example.com"})
+	assert.ErrorContains(t, err, "STARTTLS")
+}
+
+func TestSMTPTransportGivesUpOnStalledServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			// greets, then never answers
+			conn.Write([]byte("220 fake ESMTP\r\n"))
+			defer conn.Close()
+		}
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	tr, err := minimail.NewSMTPTransport(minimail.SMTPConfig{Host: "127.0.0.1", Port: port, TLS: minimail.TLSNone, Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = tr.Send(&minimail.Message{From: "noreply@example.com", To: "a@example.com"})
+	assert.ErrorContains(t, err, "timeout")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestLogTransportLeavesOutBody(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	prev := log.Logger
+	log.Logger = zap.New(core)
+	defer func() { log.Logger = prev }()
+
+	msg := &minimail.Message{To: "ada@example.com", Subject: "Reset your password", Text: "https://example.com/reset?token=secret", HTML: "<a>secret</a>"}
+	require.NoError(t, minimail.LogTransport{}.Send(msg))
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "ada@example.com", fields["to"])
+	assert.Equal(t, "Reset your password", fields["subject"])
+	for _, v := range fields {
+		assert.NotContains(t, v, "secret")
+	}
+}
@@ -0,0 +1,39 @@
+package tests
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GordenArcher/mini-github/internal/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsAreOrderedAndComplete(t *testing.T) {
+	all, err := migrations.All()
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+
+	assert.Equal(t, "create_users_and_repositories", all[0].Name)
+	assert.Contains(t, all[0].Up, `CREATE TABLE IF NOT EXISTS "users"`)
+	assert.Contains(t, all[0].Down, `DROP TABLE IF EXISTS "repositories"`)
+
+	for i, m := range all {
+		assert.Equal(t, i+1, m.Version)
+		assert.NotEmpty(t, m.Up, m.Name)
+		assert.NotEmpty(t, m.Down, m.Name)
+	}
+
+	latest, err := migrations.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, len(all), latest)
+}
+
+func TestMigrateCommandRejectsBadArguments(t *testing.T) {
+	for _, args := range [][]string{nil, {"sideways"}, {"down", "0"}, {"down", "x"}, {"to"}, {"to", "latest"}} {
+		var out bytes.Buffer
+		err := migrations.Command(nil, args, &out)
+		assert.ErrorContains(t, err, "usage: migrate", args)
+		assert.Empty(t, out.String())
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GordenArcher/mini-github/internal/policy"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinPolicies(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), memfs.New())
+	require.NoError(t, err)
+
+	commitFiles(t, r, "feat: initial", map[string]string{"big.bin": strings.Repeat("x", 2048)})
+	head := commitFiles(t, r, "add config", map[string]string{
+		"README.md":       "hello\n",
+		"config/app/.env": "SECRET=1\n",
+	})
+
+	cmt, err := r.CommitObject(head)
+	require.NoError(t, err)
+	commit, err := policy.NewCommit(cmt)
+	require.NoError(t, err)
+
+	// only files the commit itself changes are checked
+	paths := []string{}
+	for _, f := range commit.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.ElementsMatch(t, []string{"README.md", "config/app/.env"}, paths)
+	assert.Nil(t, policy.MaxFileSize(1024).Check(commit))
+
+	v := policy.ForbiddenPaths{".env"}.Check(commit)
+	require.NotNil(t, v)
+	assert.Equal(t, "config/app/.env", v.File)
+	assert.Contains(t, v.Error(), head.String()[:7])
+	assert.Nil(t, policy.ForbiddenPaths{"app/.env"}.Check(commit))
+
+	msg, err := policy.NewCommitMessage(`^(feat|fix): `)
+	require.NoError(t, err)
+	assert.NotNil(t, msg.Check(commit))
+
+	assert.Nil(t, policy.AuthorEmailDomains{"Example.com"}.Check(commit))
+	assert.NotNil(t, policy.AuthorEmailDomains{"corp.io"}.Check(commit))
+}
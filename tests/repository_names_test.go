@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/handlers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRepositoryName(t *testing.T) {
+	for _, name := range []string{"project", "mini-github", "my_repo.v2", ".github", "A", strings.Repeat("a", 100)} {
+		assert.NoError(t, db.ValidateRepositoryName(name), name)
+	}
+
+	for _, name := range []string{
+		"", ".", "..", "../x", "a/b", `a\b`, "with space", "ünïcode", "tab\t",
+		"project.git", "Project.GIT", strings.Repeat("a", 101),
+		"api", "Create", "NEW", "settings",
+	} {
+		assert.Error(t, db.ValidateRepositoryName(name), name)
+	}
+}
+
+func TestCreateRepoRejectsUnknownVisibility(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/", handlers.CreateRepo(nil, "http://localhost:8080", "2222"))
+
+	// rejected before the database is used
+	for _, visibility := range []string{"Public", "internal", " private"} {
+		body := `{"name":"project","visibility":"` + visibility + `"}`
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, visibility)
+		assert.Contains(t, rec.Body.String(), "visibility must be public or private", visibility)
+	}
+}
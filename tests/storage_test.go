@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/storage"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorageLifecycle(t *testing.T) {
+	root := t.TempDir()
+	s := storage.NewLocal(root)
+	name := storage.Location(3, "project")
+	assert.Equal(t, "3/project.git", name)
+
+	r, err := s.Create(context.Background(), name, "trunk")
+	require.NoError(t, err)
+	head, err := r.Storer.Reference("HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/trunk", head.Target().String())
+	assert.DirExists(t, filepath.Join(root, "3", "project.git", "objects"))
+
+	_, err = s.Create(context.Background(), name, "main")
+	assert.ErrorIs(t, err, storage.ErrExists)
+
+	require.NoError(t, s.Move(name, "4/renamed.git"))
+	_, err = s.Open(name)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.Open("4/renamed.git")
+	require.NoError(t, err)
+	// the first owner has no repositories left
+	assert.NoDirExists(t, filepath.Join(root, "3"))
+
+	require.NoError(t, s.Delete("4/renamed.git"))
+	assert.NoDirExists(t, filepath.Join(root, "4"))
+}
+
+func TestLocalStorageKeepsDeletedRepositoriesAside(t *testing.T) {
+	root := t.TempDir()
+	s := storage.NewLocal(root)
+	name := storage.Location(3, "project")
+	_, err := s.Create(context.Background(), name, "main")
+	require.NoError(t, err)
+
+	trash := storage.DeletedLocation(12)
+	assert.Equal(t, "deleted/12.git", trash)
+	require.NoError(t, s.Move(name, trash))
+	assert.DirExists(t, filepath.Join(root, "deleted", "12.git"))
+
+	// the name is free for a new repository meanwhile
+	_, err = s.Create(context.Background(), name, "main")
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.Move(trash, name), storage.ErrExists)
+
+	require.NoError(t, s.Delete(trash))
+	assert.NoDirExists(t, filepath.Join(root, "deleted"))
+}
+
+func TestLocalStorageRejectsEscapingNames(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "repos")
+	s := storage.NewLocal(root)
+
+	for _, name := range []string{"../x.git", "1/../../x.git", "/tmp/x.git", "", "."} {
+		_, err := s.Create(context.Background(), name, "main")
+		assert.ErrorIs(t, err, storage.ErrBadName, name)
+	}
+	_, err := os.Stat(filepath.Join(filepath.Dir(root), "x.git"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLocalStorageLegacyAbsolutePaths(t *testing.T) {
+	s := storage.NewLocal(t.TempDir())
+	legacy := filepath.Join(t.TempDir(), "old.git")
+	assert.Equal(t, legacy, s.Path(legacy))
+}
+
+func TestLocalStorageForksBorrowObjects(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s := storage.NewLocal(root)
+	parent := storage.Location(3, "lib")
+	r, err := s.Create(ctx, parent, "trunk")
+	require.NoError(t, err)
+	commit := commitFile(t, r, "trunk", "README.md", "hello")
+
+	fork := storage.Location(4, "lib")
+	f, err := s.Fork(ctx, parent, fork)
+	require.NoError(t, err)
+	head, err := f.Head()
+	require.NoError(t, err)
+	assert.Equal(t, "refs/heads/trunk", head.Name().String())
+	assert.Equal(t, commit, head.Hash())
+	// nothing was copied
+	assert.NoFileExists(t, filepath.Join(root, "4", "lib.git", "objects", commit.String()[:2], commit.String()[2:]))
+	packs, err := filepath.Glob(filepath.Join(root, "4", "lib.git", "objects", "pack", "*.pack"))
+	require.NoError(t, err)
+	assert.Empty(t, packs)
+
+	_, err = s.Fork(ctx, parent, fork)
+	assert.ErrorIs(t, err, storage.ErrExists)
+
+	// the parent moves, the fork follows it
+	require.NoError(t, s.Move(parent, storage.DeletedLocation(1)))
+	require.NoError(t, s.Borrow(fork, storage.DeletedLocation(1)))
+	f, err = s.Open(fork)
+	require.NoError(t, err)
+	_, err = f.CommitObject(commit)
+	require.NoError(t, err)
+
+	// and keeps its history once the parent is gone
+	require.NoError(t, s.Detach(ctx, fork))
+	require.NoError(t, s.Delete(storage.DeletedLocation(1)))
+	assert.NoFileExists(t, filepath.Join(root, "4", "lib.git", "objects", "info", "alternates"))
+	f, err = s.Open(fork)
+	require.NoError(t, err)
+	_, err = f.CommitObject(commit)
+	assert.NoError(t, err)
+}
+
+// commitFile commits a single file to branch of a bare repository
+func commitFile(t *testing.T, r *git.Repository, branch, name, content string) plumbing.Hash {
+	t.Helper()
+	blob := r.Storer.NewEncodedObject()
+	blob.SetType(plumbing.BlobObject)
+	w, err := blob.Writer()
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	blobHash, err := r.Storer.SetEncodedObject(blob)
+	require.NoError(t, err)
+
+	tree := &object.Tree{Entries: []object.TreeEntry{{Name: name, Mode: filemode.Regular, Hash: blobHash}}}
+	treeObj := r.Storer.NewEncodedObject()
+	require.NoError(t, tree.Encode(treeObj))
+	treeHash, err := r.Storer.SetEncodedObject(treeObj)
+	require.NoError(t, err)
+
+	sig := object.Signature{Name: "Ada", Email: "ada@example.com", When: time.Now()}
+	commit := &object.Commit{Author: sig, Committer: sig, Message: "add " + name, TreeHash: treeHash}
+	commitObj := r.Storer.NewEncodedObject()
+	require.NoError(t, commit.Encode(commitObj))
+	hash, err := r.Storer.SetEncodedObject(commitObj)
+	require.NoError(t, err)
+
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), hash)))
+	return hash
+}
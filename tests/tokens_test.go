@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GordenArcher/mini-github/internal/handlers"
+	"github.com/GordenArcher/mini-github/internal/helper/utils/tokens"
+	"github.com/GordenArcher/mini-github/internal/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePersonalAccessToken(t *testing.T) {
+	token, hash, err := tokens.Generate()
+	assert.NoError(t, err)
+	assert.True(t, tokens.IsPersonalAccessToken(token))
+	assert.Equal(t, tokens.Hash(token), hash)
+	assert.NotContains(t, hash, token)
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name   string
+		scopes []string
+		want   int
+	}{
+		{"jwt session", nil, http.StatusOK},
+		{"matching scope", []string{middleware.ScopeRepoRead}, http.StatusOK},
+		{"write implies read", []string{middleware.ScopeRepoWrite}, http.StatusOK},
+		{"missing scope", []string{middleware.ScopeUser}, http.StatusForbidden},
+		{"no scopes", []string{}, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/", func(c *gin.Context) {
+				if tc.scopes != nil {
+					c.Set("token_scopes", tc.scopes)
+				}
+			}, middleware.RequireScope(middleware.ScopeRepoRead), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			assert.Equal(t, tc.want, rec.Code)
+		})
+	}
+}
+
+func TestTokenCantGrantScopesItLacks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name      string
+		scopes    []string
+		requested string
+		want      int
+	}{
+		{"user token asks for repo:write", []string{middleware.ScopeUser}, middleware.ScopeRepoWrite, http.StatusForbidden},
+		{"user token asks for repo:read", []string{middleware.ScopeUser}, middleware.ScopeRepoRead, http.StatusForbidden},
+		{"write token asks for read", []string{middleware.ScopeUser, middleware.ScopeRepoWrite}, middleware.ScopeRepoRead, http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.POST("/", func(c *gin.Context) {
+				c.Set("user_id", uint(1))
+				c.Set("token_scopes", tc.scopes)
+			}, handlers.CreatePersonalAccessToken(nil))
+
+			// an expiry in the past stops allowed requests before they reach the database
+			body := `{"name":"ci","scopes":["` + tc.requested + `"],"expires_at":"2000-01-01T00:00:00Z"}`
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+			assert.Equal(t, tc.want, rec.Code)
+		})
+	}
+}
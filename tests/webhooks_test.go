@@ -0,0 +1,181 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GordenArcher/mini-github/internal/db"
+	"github.com/GordenArcher/mini-github/internal/gitserver"
+	"github.com/GordenArcher/mini-github/internal/webhooks"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendSignsDelivery(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("X-Receiver", "test")
+		w.Write([]byte("thanks"))
+	}))
+	defer srv.Close()
+
+	hook := &db.Webhook{URL: srv.URL, Secret: "s3cret"}
+	d := &db.WebhookDelivery{GUID: "a-guid", Event: webhooks.EventPush, RequestBody: `{"ref":"refs/heads/main"}`}
+	webhooks.Send(context.Background(), srv.Client(), hook, d)
+
+	require.NotNil(t, got)
+	assert.Equal(t, d.RequestBody, string(gotBody))
+	assert.Equal(t, webhooks.Sign("s3cret", gotBody), got.Header.Get(webhooks.HeaderSignature))
+	assert.Equal(t, "push", got.Header.Get(webhooks.HeaderEvent))
+	assert.Equal(t, "a-guid", got.Header.Get(webhooks.HeaderDelivery))
+
+	assert.Empty(t, d.Error)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, http.StatusOK, d.ResponseStatus)
+	assert.Equal(t, "thanks", d.ResponseBody)
+	assert.Equal(t, "test", d.ResponseHeaders["X-Receiver"])
+}
+
+func TestSendRecordsFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get(webhooks.HeaderSignature), "unsigned without a secret")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	d := &db.WebhookDelivery{Event: webhooks.EventPing, RequestBody: `{}`}
+	webhooks.Send(context.Background(), srv.Client(), &db.Webhook{URL: srv.URL}, d)
+
+	assert.Equal(t, http.StatusServiceUnavailable, d.ResponseStatus)
+	assert.NotEmpty(t, d.Error)
+}
+
+func TestAttemptSkipsInactiveHook(t *testing.T) {
+	received := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer srv.Close()
+
+	hook := &db.Webhook{URL: srv.URL, Active: true}
+	d := &db.WebhookDelivery{Event: webhooks.EventPing, RequestBody: `{}`, Status: webhooks.StatusPending}
+	webhooks.Attempt(context.Background(), srv.Client(), hook, d)
+	require.Equal(t, 1, received)
+	assert.Equal(t, webhooks.StatusSucceeded, d.Status)
+
+	// a retry pending when the owner turns the hook off is never sent
+	next := time.Now()
+	hook.Active = false
+	d = &db.WebhookDelivery{Event: webhooks.EventPing, RequestBody: `{}`, Status: webhooks.StatusPending, Attempts: 1, NextAttemptAt: &next}
+	webhooks.Attempt(context.Background(), srv.Client(), hook, d)
+	assert.Equal(t, 1, received)
+	assert.Equal(t, webhooks.StatusFailed, d.Status)
+	assert.Equal(t, "webhook inactive", d.Error)
+	assert.Nil(t, d.NextAttemptAt)
+	assert.Equal(t, 1, d.Attempts)
+}
+
+func TestSign(t *testing.T) {
+	// the example from GitHub's webhook validation docs
+	assert.Equal(t,
+		"sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+		webhooks.Sign("It's a Secret to Everybody", []byte("Hello, World!")))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, webhooks.Backoff(1))
+	assert.Equal(t, time.Minute, webhooks.Backoff(2))
+	assert.Equal(t, 30*time.Minute, webhooks.Backoff(10))
+	assert.Equal(t, 30*time.Minute, webhooks.Backoff(100))
+}
+
+func TestSubscribes(t *testing.T) {
+	hook := &db.Webhook{Events: []string{webhooks.EventPush}}
+	assert.True(t, webhooks.Subscribes(hook, webhooks.EventPush))
+	assert.False(t, webhooks.Subscribes(hook, webhooks.EventBranchDeleted))
+	assert.True(t, webhooks.Subscribes(hook, webhooks.EventPing))
+
+	hook.Events = []string{webhooks.EventAll}
+	assert.True(t, webhooks.Subscribes(hook, webhooks.EventRepositoryCreated))
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("delivered to a loopback address")
+	}))
+	defer srv.Close()
+
+	client := webhooks.NewClient()
+	for _, url := range []string{
+		srv.URL,
+		"http://localhost:1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://192.168.1.1/",
+		"http://[::1]:1/",
+		"http://[::ffff:127.0.0.1]:1/",
+		"http://0.0.0.0:1/",
+	} {
+		d := &db.WebhookDelivery{Event: webhooks.EventPing, RequestBody: `{}`}
+		webhooks.Send(context.Background(), client, &db.Webhook{URL: url}, d)
+		assert.Contains(t, d.Error, "private addresses", url)
+		assert.Zero(t, d.ResponseStatus, url)
+	}
+
+	// a redirect is recorded as the response rather than followed
+	assert.ErrorIs(t, client.CheckRedirect(nil, nil), http.ErrUseLastResponse)
+}
+
+func tagAt(t *testing.T, r *git.Repository, name string, target plumbing.Hash, kind plumbing.ObjectType) plumbing.Hash {
+	t.Helper()
+	sig := object.Signature{Name: "Ada", Email: "ada@example.com"}
+	tag := &object.Tag{Name: name, Tagger: sig, Message: name, TargetType: kind, Target: target}
+	obj := r.Storer.NewEncodedObject()
+	require.NoError(t, tag.Encode(obj))
+	hash, err := r.Storer.SetEncodedObject(obj)
+	require.NoError(t, err)
+	return hash
+}
+
+func TestPushPayloadForcedTags(t *testing.T) {
+	r, err := git.Init(memory.NewStorage(), nil)
+	require.NoError(t, err)
+
+	root := commitAt(t, r, "root", 0)
+	a := commitAt(t, r, "a", 1, root)
+	b := commitAt(t, r, "b", 2, root)
+	rootCommit, err := r.CommitObject(root)
+	require.NoError(t, err)
+
+	tagA := tagAt(t, r, "v1", a, plumbing.CommitObject)
+	tagB := tagAt(t, r, "v1", b, plumbing.CommitObject)
+	tagTree := tagAt(t, r, "v1", rootCommit.TreeHash, plumbing.TreeObject)
+
+	p := &gitserver.Push{Git: r}
+	cases := []struct {
+		name   string
+		u      gitserver.RefUpdate
+		forced bool
+	}{
+		{"branch fast-forward", gitserver.RefUpdate{Name: "refs/heads/main", Old: root, New: a}, false},
+		{"branch rewound", gitserver.RefUpdate{Name: "refs/heads/main", Old: a, New: b}, true},
+		{"annotated tag moved", gitserver.RefUpdate{Name: "refs/tags/v1", Old: tagA, New: tagB}, true},
+		{"tag of a tree moved", gitserver.RefUpdate{Name: "refs/tags/v1", Old: tagTree, New: tagA}, false},
+	}
+	for _, tc := range cases {
+		payload, err := webhooks.PushPayload(p, tc.u)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.forced, payload["forced"], tc.name)
+	}
+}